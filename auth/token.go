@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrBadToken = errors.New("access token is invalid or expired")
+
+// Verifier reads user id from HS256 signed JWT access tokens issued by
+// profile service.
+type Verifier struct {
+	secret []byte
+}
+
+// NewVerifier returns nil for empty secret, which verifies no tokens.
+func NewVerifier(secret string) *Verifier {
+	if secret == "" {
+		return nil
+	}
+	return &Verifier{secret: []byte(secret)}
+}
+
+type claims struct {
+	Sub       string   `json:"sub"`
+	UserId    string   `json:"user_id"`
+	ExpiresAt *float64 `json:"exp"`
+	NotBefore *float64 `json:"nbf"`
+}
+
+func (v *Verifier) UserId(token string) (string, error) {
+	if v == nil || token == "" {
+		return "", ErrBadToken
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", ErrBadToken
+	}
+	enc := base64.RawURLEncoding
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if raw, err := enc.DecodeString(parts[0]); err != nil || json.Unmarshal(raw, &header) != nil || header.Alg != "HS256" {
+		return "", ErrBadToken
+	}
+
+	mac := hmac.New(sha256.New, v.secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	sig, err := enc.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(sig, mac.Sum(nil)) {
+		return "", ErrBadToken
+	}
+
+	var c claims
+	if raw, err := enc.DecodeString(parts[1]); err != nil || json.Unmarshal(raw, &c) != nil {
+		return "", ErrBadToken
+	}
+	now := float64(time.Now().Unix())
+	if (c.ExpiresAt != nil && now >= *c.ExpiresAt) || (c.NotBefore != nil && now < *c.NotBefore) {
+		return "", ErrBadToken
+	}
+
+	if c.UserId != "" {
+		return c.UserId, nil
+	}
+	if c.Sub != "" {
+		return c.Sub, nil
+	}
+	return "", ErrBadToken
+}
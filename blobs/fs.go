@@ -0,0 +1,164 @@
+package blobs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FS keeps blobs as files under dir/data, and their content type and expiry
+// as JSON under dir/meta.
+type FS struct {
+	dir    string
+	signer *Signer
+}
+
+type fsMeta struct {
+	ContentType string    `json:"content_type"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+func NewFS(dir string, signer *Signer) (*FS, error) {
+	if dir == "" {
+		return nil, errors.New("fs blob store needs a directory")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FS{dir: dir, signer: signer}, nil
+}
+
+func (f *FS) dataPath(key string) string {
+	return filepath.Join(f.dir, "data", filepath.FromSlash(key))
+}
+
+func (f *FS) metaPath(key string) string {
+	return filepath.Join(f.dir, "meta", filepath.FromSlash(key)+".json")
+}
+
+// writeFile replaces path atomically, so readers never see it half written.
+func writeFile(path string, data io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = io.Copy(tmp, data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (f *FS) Put(ctx context.Context, key string, blob *Blob) error {
+	return f.PutReader(ctx, key, bytes.NewReader(blob.Data), int64(len(blob.Data)), blob)
+}
+
+// PutReader writes data before meta, blobs without meta are not found.
+func (f *FS) PutReader(_ context.Context, key string, body io.Reader, size int64, blob *Blob) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	meta, err := json.Marshal(fsMeta{ContentType: blob.ContentType, ExpiresAt: blob.ExpiresAt})
+	if err != nil {
+		return err
+	}
+	if err = writeFile(f.dataPath(key), io.LimitReader(body, size)); err != nil {
+		return err
+	}
+	return writeFile(f.metaPath(key), bytes.NewReader(meta))
+}
+
+func (f *FS) readMeta(key string) (*fsMeta, error) {
+	raw, err := os.ReadFile(f.metaPath(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	meta := new(fsMeta)
+	return meta, json.Unmarshal(raw, meta)
+}
+
+func (f *FS) Get(ctx context.Context, key string) (*Blob, error) {
+	if err := checkKey(key); err != nil {
+		return nil, ErrNotFound
+	}
+	meta, err := f.readMeta(key)
+	if err != nil {
+		return nil, err
+	}
+	blob := &Blob{ContentType: meta.ContentType, ExpiresAt: meta.ExpiresAt}
+	if blob.expired(time.Now()) {
+		f.Delete(ctx, key)
+		return nil, ErrNotFound
+	}
+
+	blob.Data, err = os.ReadFile(f.dataPath(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return blob, err
+}
+
+// Delete removes meta first, so a blob half deleted is not found.
+func (f *FS) Delete(_ context.Context, key string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	if err := os.Remove(f.metaPath(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := os.Remove(f.dataPath(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (f *FS) SignedURL(_ context.Context, key string, expiry time.Duration) (string, error) {
+	if err := checkKey(key); err != nil {
+		return "", err
+	}
+	return f.signer.URL(key, time.Now().Add(expiry)), nil
+}
+
+// Sweep deletes expired blobs.
+func (f *FS) Sweep(ctx context.Context) error {
+	root := filepath.Join(f.dir, "meta")
+	now := time.Now()
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".json") {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		rel, _ := filepath.Rel(root, strings.TrimSuffix(path, ".json"))
+		key := filepath.ToSlash(rel)
+		meta, err := f.readMeta(key)
+		if err != nil || !(&Blob{ExpiresAt: meta.ExpiresAt}).expired(now) {
+			return nil
+		}
+		return f.Delete(ctx, key)
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
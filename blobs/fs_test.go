@@ -0,0 +1,87 @@
+package blobs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFS(t *testing.T) {
+	store, err := NewFS(t.TempDir(), NewSigner("secret", "/blobs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, store)
+}
+
+func TestFSSweep(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := NewFS(dir, NewSigner("secret", "/blobs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = store.Sweep(ctx); err != nil {
+		t.Fatalf("Sweep of empty store: %v", err)
+	}
+
+	store.Put(ctx, "graphs/old.png", &Blob{Data: []byte("old"), ExpiresAt: time.Now().Add(-time.Second)})
+	store.Put(ctx, "graphs/new.png", &Blob{Data: []byte("new"), ExpiresAt: time.Now().Add(time.Hour)})
+	store.Put(ctx, "exports/kept.csv", &Blob{Data: []byte("kept")})
+	if err = store.Sweep(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"data/graphs/old.png", "meta/graphs/old.png.json"} {
+		if _, err := os.Stat(filepath.Join(dir, path)); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s of expired blob is left: %v", path, err)
+		}
+	}
+	for _, key := range []string{"graphs/new.png", "exports/kept.csv"} {
+		if _, err := store.Get(ctx, key); err != nil {
+			t.Errorf("Get(%q) after sweep: %v", key, err)
+		}
+	}
+}
+
+func TestFSWithoutMeta(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, _ := NewFS(dir, NewSigner("secret", "/blobs"))
+	store.Put(ctx, "graphs/a.png", &Blob{Data: []byte("a")})
+
+	// a blob whose put was cut before meta is written isn't there
+	os.Remove(filepath.Join(dir, "meta", "graphs", "a.png.json"))
+	if _, err := store.Get(ctx, "graphs/a.png"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestFSNeedsDir(t *testing.T) {
+	if _, err := NewFS("", nil); err == nil {
+		t.Error("NewFS without a directory succeeded")
+	}
+}
+
+func TestFSRejectsEscapes(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, _ := NewFS(filepath.Join(dir, "store"), NewSigner("secret", "/blobs"))
+
+	// a blob outside the store, were ../../secret joined to data and meta
+	os.WriteFile(filepath.Join(dir, "secret"), []byte("secret"), 0o644)
+	os.WriteFile(filepath.Join(dir, "secret.json"), []byte(`{"content_type":"text/plain"}`), 0o644)
+
+	if blob, err := store.Get(ctx, "../../secret"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get = %+v, %v, want ErrNotFound", blob, err)
+	}
+	if err := store.Delete(ctx, "../../secret"); err == nil {
+		t.Error("Delete outside the store succeeded")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "secret")); err != nil {
+		t.Errorf("file outside the store is gone: %v", err)
+	}
+}
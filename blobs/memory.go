@@ -0,0 +1,127 @@
+package blobs
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+const memorySweepEvery = time.Minute
+
+// Memory keeps blobs in memory, for a single gateway instance. Once they take
+// more than maxBytes the expired ones are dropped, then those expiring soonest.
+type Memory struct {
+	signer   *Signer
+	maxBytes int64
+
+	mu      sync.Mutex
+	blobs   map[string]*Blob
+	size    int64
+	sweptAt time.Time
+}
+
+// NewMemory makes a store of at most maxBytes of data, unbounded if it isn't
+// positive.
+func NewMemory(signer *Signer, maxBytes int64) *Memory {
+	return &Memory{signer: signer, maxBytes: maxBytes, blobs: map[string]*Blob{}, sweptAt: time.Now()}
+}
+
+func (m *Memory) Put(_ context.Context, key string, blob *Blob) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	if m.maxBytes > 0 && int64(len(blob.Data)) > m.maxBytes {
+		return fmt.Errorf("blob %q of %d bytes is larger than the store", key, len(blob.Data))
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// expired blobs are dropped now and then, Get ignores them meanwhile
+	now := time.Now()
+	if now.Sub(m.sweptAt) > memorySweepEvery {
+		m.sweep(now)
+	}
+
+	m.remove(key)
+	if m.maxBytes > 0 && m.size+int64(len(blob.Data)) > m.maxBytes {
+		m.sweep(now)
+		m.evict(m.maxBytes - int64(len(blob.Data)))
+	}
+
+	stored := *blob
+	m.blobs[key] = &stored
+	m.size += int64(len(stored.Data))
+	return nil
+}
+
+func (m *Memory) sweep(now time.Time) {
+	for k, b := range m.blobs {
+		if b.expired(now) {
+			m.remove(k)
+		}
+	}
+	m.sweptAt = now
+}
+
+// evict drops blobs expiring soonest, those kept until deleted last, until
+// at most size bytes are left.
+func (m *Memory) evict(size int64) {
+	keys := make([]string, 0, len(m.blobs))
+	for k := range m.blobs {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, func(a, b string) int {
+		ea, eb := m.blobs[a].ExpiresAt, m.blobs[b].ExpiresAt
+		switch {
+		case ea.IsZero() && eb.IsZero():
+			return 0
+		case ea.IsZero():
+			return 1
+		case eb.IsZero():
+			return -1
+		}
+		return ea.Compare(eb)
+	})
+	for _, k := range keys {
+		if m.size <= size {
+			return
+		}
+		m.remove(k)
+	}
+}
+
+func (m *Memory) remove(key string) {
+	if b, ok := m.blobs[key]; ok {
+		m.size -= int64(len(b.Data))
+		delete(m.blobs, key)
+	}
+}
+
+func (m *Memory) Get(_ context.Context, key string) (*Blob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	blob, ok := m.blobs[key]
+	if !ok || blob.expired(time.Now()) {
+		return nil, ErrNotFound
+	}
+	found := *blob
+	return &found, nil
+}
+
+func (m *Memory) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remove(key)
+	return nil
+}
+
+func (m *Memory) SignedURL(_ context.Context, key string, expiry time.Duration) (string, error) {
+	if err := checkKey(key); err != nil {
+		return "", err
+	}
+	return m.signer.URL(key, time.Now().Add(expiry)), nil
+}
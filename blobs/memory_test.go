@@ -0,0 +1,52 @@
+package blobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemory(t *testing.T) {
+	testStore(t, NewMemory(NewSigner("secret", "/blobs"), 0))
+}
+
+func TestMemoryEvicts(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory(NewSigner("secret", "/blobs"), 10)
+	now := time.Now()
+	put := func(key string, size int, expiresAt time.Time) {
+		t.Helper()
+		if err := m.Put(ctx, key, &Blob{Data: make([]byte, size), ExpiresAt: expiresAt}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	kept := func(key string) bool {
+		_, err := m.Get(ctx, key)
+		return !errors.Is(err, ErrNotFound)
+	}
+
+	put("kept", 3, time.Time{})
+	put("later", 3, now.Add(2*time.Hour))
+	put("sooner", 3, now.Add(time.Hour))
+	// replacing a blob frees its old size
+	put("sooner", 3, now.Add(time.Hour))
+	put("new", 3, now.Add(time.Hour))
+	if kept("sooner") || !kept("later") || !kept("kept") || !kept("new") {
+		t.Errorf("sooner expiring blob should be evicted only, kept sooner %v, later %v, kept %v, new %v",
+			kept("sooner"), kept("later"), kept("kept"), kept("new"))
+	}
+
+	put("expired", 1, now.Add(-time.Second))
+	put("small", 1, time.Time{})
+	if !kept("later") || !kept("new") {
+		t.Error("expired blob should be evicted before live ones")
+	}
+	if m.size > 10 {
+		t.Errorf("size = %d, want at most 10", m.size)
+	}
+
+	if err := m.Put(ctx, "huge", &Blob{Data: make([]byte, 11)}); err == nil {
+		t.Error("blob larger than the store is put")
+	}
+}
@@ -0,0 +1,121 @@
+package blobs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// expiresAtMeta is the user metadata keeping blob expiry. Objects aren't
+// removed when they expire, a bucket lifecycle rule should do that.
+const expiresAtMeta = "Expires-At"
+
+type S3Options struct {
+	Endpoint  string
+	Bucket    string
+	Region    string
+	AccessKey string
+	SecretKey string
+	UseSSL    bool
+}
+
+// S3 keeps blobs in a bucket of an S3 compatible storage.
+type S3 struct {
+	client *minio.Client
+	bucket string
+}
+
+func NewS3(opts S3Options) (*S3, error) {
+	if opts.Endpoint == "" || opts.Bucket == "" {
+		return nil, errors.New("s3 blob store needs an endpoint and a bucket")
+	}
+	client, err := minio.New(opts.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(opts.AccessKey, opts.SecretKey, ""),
+		Secure: opts.UseSSL,
+		Region: opts.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &S3{client: client, bucket: opts.Bucket}, nil
+}
+
+func (s *S3) Put(ctx context.Context, key string, blob *Blob) error {
+	return s.PutReader(ctx, key, bytes.NewReader(blob.Data), int64(len(blob.Data)), blob)
+}
+
+func (s *S3) PutReader(ctx context.Context, key string, body io.Reader, size int64, blob *Blob) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	opts := minio.PutObjectOptions{ContentType: blob.ContentType}
+	if !blob.ExpiresAt.IsZero() {
+		opts.UserMetadata = map[string]string{expiresAtMeta: blob.ExpiresAt.UTC().Format(time.RFC3339)}
+		opts.Expires = blob.ExpiresAt
+	}
+	_, err := s.client.PutObject(ctx, s.bucket, key, body, size, opts)
+	return err
+}
+
+func notFound(err error) bool {
+	resp := minio.ToErrorResponse(err)
+	return resp.StatusCode == http.StatusNotFound || resp.Code == "NoSuchKey"
+}
+
+func (s *S3) Get(ctx context.Context, key string) (*Blob, error) {
+	if err := checkKey(key); err != nil {
+		return nil, ErrNotFound
+	}
+	obj, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+
+	info, err := obj.Stat()
+	if notFound(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	blob := &Blob{ContentType: info.ContentType}
+	if raw := info.UserMetadata[expiresAtMeta]; raw != "" {
+		blob.ExpiresAt, _ = time.Parse(time.RFC3339, raw)
+	}
+	if blob.expired(time.Now()) {
+		return nil, ErrNotFound
+	}
+
+	blob.Data, err = io.ReadAll(obj)
+	if notFound(err) {
+		return nil, ErrNotFound
+	}
+	return blob, err
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	return s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{})
+}
+
+// SignedURL presigns a GET of the storage itself, clients download the blob
+// from there.
+func (s *S3) SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	if err := checkKey(key); err != nil {
+		return "", err
+	}
+	u, err := s.client.PresignedGetObject(ctx, s.bucket, key, expiry, nil)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
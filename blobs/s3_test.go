@@ -0,0 +1,179 @@
+package blobs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeObject struct {
+	data   []byte
+	header http.Header
+}
+
+// fakeS3 serves objects of one bucket with path style requests, enough for
+// the calls S3 makes. Signatures aren't checked.
+type fakeS3 struct {
+	bucket string
+
+	mu      sync.Mutex
+	objects map[string]fakeObject
+}
+
+// readChunked decodes aws-chunked bodies of streaming signed puts.
+func readChunked(body io.Reader) ([]byte, error) {
+	var data []byte
+	r := bufio.NewReader(body)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		sizeHex, _, _ := strings.Cut(strings.TrimSpace(line), ";")
+		size, err := strconv.ParseInt(sizeHex, 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return data, nil
+		}
+		chunk := make([]byte, size+2)
+		if _, err = io.ReadFull(r, chunk); err != nil {
+			return nil, err
+		}
+		data = append(data, chunk[:size]...)
+	}
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key, ok := strings.CutPrefix(r.URL.Path, "/"+f.bucket+"/")
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `<Error><Code>NoSuchBucket</Code></Error>`)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		var data []byte
+		var err error
+		if strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+			data, err = readChunked(r.Body)
+		} else {
+			data, err = io.ReadAll(r.Body)
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		header := http.Header{}
+		for name, values := range r.Header {
+			if strings.HasPrefix(name, "X-Amz-Meta-") || name == "Content-Type" {
+				header[name] = values
+			}
+		}
+		f.objects[key] = fakeObject{data: data, header: header}
+		w.Header().Set("ETag", `"etag"`)
+	case http.MethodGet, http.MethodHead:
+		obj, ok := f.objects[key]
+		if !ok {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, `<Error><Code>NoSuchKey</Code><Key>%s</Key></Error>`, key)
+			return
+		}
+		for name, values := range obj.header {
+			w.Header()[name] = values
+		}
+		w.Header().Set("ETag", `"etag"`)
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		w.Header().Set("Content-Length", strconv.Itoa(len(obj.data)))
+		if r.Method == http.MethodGet {
+			w.Write(obj.data)
+		}
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func newFakeS3(t *testing.T) (*S3, *fakeS3) {
+	t.Helper()
+	fake := &fakeS3{bucket: "blobs", objects: map[string]fakeObject{}}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	store, err := NewS3(S3Options{
+		Endpoint:  strings.TrimPrefix(srv.URL, "http://"),
+		Bucket:    fake.bucket,
+		Region:    "us-east-1",
+		AccessKey: "access",
+		SecretKey: "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, fake
+}
+
+func TestS3(t *testing.T) {
+	store, _ := newFakeS3(t)
+	testStore(t, store)
+}
+
+func TestS3KeepsExpiry(t *testing.T) {
+	store, fake := newFakeS3(t)
+	expiresAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := store.Put(context.Background(), "graphs/a.png", &Blob{Data: []byte("a"), ExpiresAt: expiresAt}); err != nil {
+		t.Fatal(err)
+	}
+	if got := fake.objects["graphs/a.png"].header.Get("X-Amz-Meta-Expires-At"); got != "2030-01-02T03:04:05Z" {
+		t.Errorf("Expires-At metadata = %q", got)
+	}
+}
+
+func TestS3SignedURL(t *testing.T) {
+	store, _ := newFakeS3(t)
+	ctx := context.Background()
+	store.Put(ctx, "exports/1/listings.csv", &Blob{Data: []byte("a,b\n"), ContentType: "text/csv"})
+
+	raw, err := store.SignedURL(ctx, "exports/1/listings.csv", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(raw)
+	if u.Path != "/blobs/exports/1/listings.csv" || u.Query().Get("X-Amz-Expires") != "60" || u.Query().Get("X-Amz-Signature") == "" {
+		t.Errorf("SignedURL = %q, want a presigned GET of the object for a minute", raw)
+	}
+
+	resp, err := http.Get(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if !bytes.Equal(body, []byte("a,b\n")) {
+		t.Errorf("downloaded %q", body)
+	}
+}
+
+func TestS3NeedsBucket(t *testing.T) {
+	if _, err := NewS3(S3Options{Endpoint: "localhost:9000"}); err == nil {
+		t.Error("NewS3 without a bucket succeeded")
+	}
+}
@@ -0,0 +1,85 @@
+package blobs
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Signer makes expiring URLs of blobs the gateway serves itself, under base
+// path. With empty secret a random one is used and URLs become invalid after
+// restart.
+type Signer struct {
+	secret []byte
+	base   string
+}
+
+func NewSigner(secret, base string) *Signer {
+	key := []byte(secret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		rand.Read(key)
+	}
+	return &Signer{secret: key, base: strings.TrimSuffix(base, "/")}
+}
+
+func (s *Signer) sign(key string, expires int64) string {
+	mac := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(mac, "%s\n%d", key, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *Signer) URL(key string, expiresAt time.Time) string {
+	expires := expiresAt.Unix()
+	return fmt.Sprintf("%s/%s?expires=%d&signature=%s", s.base, key, expires, s.sign(key, expires))
+}
+
+// verify returns key of signed URL r requests.
+func (s *Signer) verify(r *http.Request) (string, error) {
+	key, ok := strings.CutPrefix(r.URL.Path, s.base+"/")
+	if !ok {
+		return "", errors.New("not a blob URL")
+	}
+	expires, err := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+	if err != nil {
+		return "", errors.New("bad expires")
+	}
+	if !hmac.Equal([]byte(r.URL.Query().Get("signature")), []byte(s.sign(key, expires))) {
+		return "", errors.New("bad signature")
+	}
+	if time.Now().Unix() > expires {
+		return "", errors.New("URL has expired")
+	}
+	return key, nil
+}
+
+// Handler serves blobs of store by URLs signed by s.
+func (s *Signer) Handler(store Store) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, err := s.verify(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		blob, err := store.Get(r.Context(), key)
+		if errors.Is(err, ErrNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "can't read blob", http.StatusBadGateway)
+			return
+		}
+
+		w.Header().Set("Content-Type", blob.ContentType)
+		w.Header().Set("Cache-Control", "private, no-store")
+		w.Write(blob.Data)
+	})
+}
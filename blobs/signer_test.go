@@ -0,0 +1,70 @@
+package blobs
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignerHandler(t *testing.T) {
+	ctx := context.Background()
+	signer := NewSigner("secret", "/blobs/")
+	store := NewMemory(signer, 0)
+	store.Put(ctx, "exports/1/listings.csv", &Blob{Data: []byte("a,b\n"), ContentType: "text/csv"})
+	store.Put(ctx, "exports/old.csv", &Blob{Data: []byte("old"), ExpiresAt: time.Now().Add(-time.Second)})
+
+	valid := signer.URL("exports/1/listings.csv", time.Now().Add(time.Minute))
+	if !strings.HasPrefix(valid, "/blobs/exports/1/listings.csv?") {
+		t.Fatalf("URL = %q, want it under /blobs/", valid)
+	}
+	u, _ := url.Parse(valid)
+	q := u.Query()
+	tampered := func(key, value string) string {
+		q := u.Query()
+		q.Set(key, value)
+		return u.Path + "?" + q.Encode()
+	}
+
+	tests := []struct {
+		name   string
+		url    string
+		status int
+	}{
+		{"valid", valid, http.StatusOK},
+		{"expired", signer.URL("exports/1/listings.csv", time.Now().Add(-time.Minute)), http.StatusForbidden},
+		{"expiry extended", tampered("expires", "99999999999"), http.StatusForbidden},
+		{"bad signature", tampered("signature", strings.Repeat("0", 64)), http.StatusForbidden},
+		{"no signature", u.Path + "?expires=" + q.Get("expires"), http.StatusForbidden},
+		{"bad expires", tampered("expires", "soon"), http.StatusForbidden},
+		{"other key", "/blobs/exports/2/listings.csv?" + q.Encode(), http.StatusForbidden},
+		{"other secret", NewSigner("other", "/blobs").URL("exports/1/listings.csv", time.Now().Add(time.Minute)), http.StatusForbidden},
+		{"not a blob URL", "/other/exports/1/listings.csv?" + q.Encode(), http.StatusForbidden},
+		{"blob expired", signer.URL("exports/old.csv", time.Now().Add(time.Minute)), http.StatusNotFound},
+		{"path escape", signer.URL("../config.yaml", time.Now().Add(time.Minute)), http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			signer.Handler(store).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.status == http.StatusOK && (rec.Body.String() != "a,b\n" || rec.Header().Get("Content-Type") != "text/csv") {
+				t.Errorf("served %q as %q", rec.Body, rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestSignerRandomSecret(t *testing.T) {
+	u := NewSigner("", "/blobs").URL("graphs/a.png", time.Now().Add(time.Minute))
+	rec := httptest.NewRecorder()
+	NewSigner("", "/blobs").Handler(NewMemory(nil, 0)).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, u, nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("URL of one random secret signer served by another with status %d", rec.Code)
+	}
+}
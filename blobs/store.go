@@ -0,0 +1,104 @@
+package blobs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+var ErrNotFound = errors.New("blob not found or expired")
+
+// Blob is a stored artifact, zero ExpiresAt keeps it until deleted.
+type Blob struct {
+	Data        []byte
+	ContentType string
+	ExpiresAt   time.Time
+}
+
+func (b *Blob) expired(now time.Time) bool {
+	return !b.ExpiresAt.IsZero() && now.After(b.ExpiresAt)
+}
+
+// Store keeps generated artifacts like prediction graphs and exports under
+// slash separated keys.
+type Store interface {
+	Put(ctx context.Context, key string, blob *Blob) error
+	// Get returns ErrNotFound for missing and expired blobs.
+	Get(ctx context.Context, key string) (*Blob, error)
+	Delete(ctx context.Context, key string) error
+	// SignedURL lets anyone holding it download the blob within expiry.
+	SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error)
+}
+
+// ReaderPutter is a Store which can put blobs without holding them in memory.
+type ReaderPutter interface {
+	// PutReader puts size bytes read from body as the data of blob.
+	PutReader(ctx context.Context, key string, body io.Reader, size int64, blob *Blob) error
+}
+
+// PutReader puts size bytes of body to store as the data of blob, reading
+// them in memory only when store can't stream them.
+func PutReader(ctx context.Context, store Store, key string, body io.Reader, size int64, blob *Blob) error {
+	if putter, ok := store.(ReaderPutter); ok {
+		return putter.PutReader(ctx, key, body, size, blob)
+	}
+	data, err := io.ReadAll(io.LimitReader(body, size))
+	if err != nil {
+		return err
+	}
+	read := *blob
+	read.Data = data
+	return store.Put(ctx, key, &read)
+}
+
+// Sweeper is a Store which needs expired blobs removed from time to time.
+type Sweeper interface {
+	Sweep(ctx context.Context) error
+}
+
+// Kinds are the stores BLOB_STORE can name.
+var Kinds = []string{"memory", "fs", "s3"}
+
+type Options struct {
+	// Dir is the root of fs store
+	Dir string
+	// MemoryMaxBytes bounds memory store
+	MemoryMaxBytes int64
+	// Signer makes URLs of memory and fs stores, served by the gateway
+	Signer *Signer
+	S3     S3Options
+}
+
+func New(kind string, opts Options) (Store, error) {
+	switch kind {
+	case "memory":
+		return NewMemory(opts.Signer, opts.MemoryMaxBytes), nil
+	case "fs":
+		return NewFS(opts.Dir, opts.Signer)
+	case "s3":
+		return NewS3(opts.S3)
+	default:
+		return nil, fmt.Errorf("unknown blob store %q, expected %s", kind, strings.Join(Kinds, ", "))
+	}
+}
+
+// ContentId names data by its content.
+func ContentId(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}
+
+// checkKey rejects keys which could escape the store root.
+func checkKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, `\`) ||
+		slices.ContainsFunc(strings.Split(key, "/"), func(part string) bool { return part == "" || part == "." || part == ".." }) {
+		return fmt.Errorf("bad blob key %q", key)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package blobs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testStore checks the behaviour every Store shares.
+func testStore(t *testing.T, store Store) {
+	ctx := context.Background()
+
+	t.Run("PutGet", func(t *testing.T) {
+		put := &Blob{Data: []byte("graph"), ContentType: "image/png", ExpiresAt: time.Now().Add(time.Hour).Truncate(time.Second)}
+		if err := store.Put(ctx, "graphs/a.png", put); err != nil {
+			t.Fatal(err)
+		}
+		got, err := store.Get(ctx, "graphs/a.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got.Data) != "graph" || got.ContentType != "image/png" || !got.ExpiresAt.Equal(put.ExpiresAt) {
+			t.Errorf("Get = %+v, want %+v", got, put)
+		}
+
+		if err = store.Put(ctx, "graphs/a.png", &Blob{Data: []byte("other"), ContentType: "image/png"}); err != nil {
+			t.Fatal(err)
+		}
+		if got, err = store.Get(ctx, "graphs/a.png"); err != nil || string(got.Data) != "other" || !got.ExpiresAt.IsZero() {
+			t.Errorf("Get after replace = %+v, %v, want other kept until deleted", got, err)
+		}
+	})
+
+	t.Run("PutReader", func(t *testing.T) {
+		blob := &Blob{ContentType: "text/csv"}
+		if err := PutReader(ctx, store, "exports/1/listings.csv", strings.NewReader("a,b\n1,2\nignored"), 8, blob); err != nil {
+			t.Fatal(err)
+		}
+		got, err := store.Get(ctx, "exports/1/listings.csv")
+		if err != nil || string(got.Data) != "a,b\n1,2\n" || got.ContentType != "text/csv" {
+			t.Errorf("Get = %+v, %v, want the 8 bytes put", got, err)
+		}
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		if err := store.Put(ctx, "graphs/old.png", &Blob{Data: []byte("old"), ExpiresAt: time.Now().Add(-time.Second)}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.Get(ctx, "graphs/old.png"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Get of expired blob error = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		if err := store.Put(ctx, "graphs/gone.png", &Blob{Data: []byte("gone")}); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Delete(ctx, "graphs/gone.png"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.Get(ctx, "graphs/gone.png"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Get of deleted blob error = %v, want ErrNotFound", err)
+		}
+		if err := store.Delete(ctx, "graphs/never.png"); err != nil {
+			t.Errorf("Delete of missing blob: %v", err)
+		}
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		if _, err := store.Get(ctx, "graphs/missing.png"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Get error = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("BadKeys", func(t *testing.T) {
+		for _, key := range badKeys {
+			if err := store.Put(ctx, key, &Blob{Data: []byte("x")}); err == nil {
+				t.Errorf("Put(%q) succeeded", key)
+			}
+			if err := PutReader(ctx, store, key, bytes.NewReader([]byte("x")), 1, &Blob{}); err == nil {
+				t.Errorf("PutReader(%q) succeeded", key)
+			}
+			if _, err := store.SignedURL(ctx, key, time.Minute); err == nil {
+				t.Errorf("SignedURL(%q) succeeded", key)
+			}
+		}
+	})
+}
+
+var badKeys = []string{"", "/etc/passwd", "../secret", "graphs/../../secret", "graphs/./a", "graphs//a", `graphs\..\a`, "graphs/"}
+
+func TestCheckKey(t *testing.T) {
+	for _, key := range badKeys {
+		if err := checkKey(key); err == nil {
+			t.Errorf("checkKey(%q) accepted it", key)
+		}
+	}
+	for _, key := range []string{"graphs/a.png", "exports/1f/listings.csv", "a..b/.c"} {
+		if err := checkKey(key); err != nil {
+			t.Errorf("checkKey(%q): %v", key, err)
+		}
+	}
+}
+
+func TestContentId(t *testing.T) {
+	a, b := ContentId([]byte("a")), ContentId([]byte("b"))
+	if a == b || a != ContentId([]byte("a")) || len(a) != 32 {
+		t.Errorf("ContentId of a = %q, of b = %q, want stable 32 hex digits", a, b)
+	}
+}
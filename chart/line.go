@@ -0,0 +1,112 @@
+package chart
+
+import (
+	"math"
+	"strconv"
+)
+
+const (
+	width  = 800
+	height = 400
+
+	marginLeft   = 80
+	marginRight  = 30
+	marginTop    = 50
+	marginBottom = 50
+
+	maxXTicks = 12
+	yTicks    = 5
+)
+
+// Line is a line chart of Y values at X. X is expected to be ascending.
+type Line struct {
+	Title  string
+	XLabel string
+	YLabel string
+	X      []int
+	Y      []int
+}
+
+// frame maps values of a line to pixels of the plot area.
+type frame struct {
+	xMin, xMax int
+	yMin, yMax int
+	yStep      int
+}
+
+func newFrame(l Line) frame {
+	f := frame{xMin: l.X[0], xMax: l.X[len(l.X)-1], yMin: l.Y[0], yMax: l.Y[0]}
+	for _, y := range l.Y {
+		f.yMin, f.yMax = min(f.yMin, y), max(f.yMax, y)
+	}
+
+	f.yStep = niceStep(float64(f.yMax-f.yMin) / float64(yTicks-1))
+	f.yMin = int(math.Floor(float64(f.yMin)/float64(f.yStep))) * f.yStep
+	f.yMax = int(math.Ceil(float64(f.yMax)/float64(f.yStep))) * f.yStep
+	if f.yMax == f.yMin {
+		f.yMax += f.yStep
+	}
+	return f
+}
+
+// niceStep rounds raw up to 1, 2 or 5 times a power of ten.
+func niceStep(raw float64) int {
+	if raw < 1 {
+		return 1
+	}
+	mag := math.Pow(10, math.Floor(math.Log10(raw)))
+	for _, m := range []float64{1, 2, 5, 10} {
+		if raw <= m*mag {
+			return int(m * mag)
+		}
+	}
+	return int(10 * mag)
+}
+
+func (f frame) px(x int) int {
+	if f.xMax == f.xMin {
+		return (marginLeft + width - marginRight) / 2
+	}
+	return marginLeft + (x-f.xMin)*(width-marginLeft-marginRight)/(f.xMax-f.xMin)
+}
+
+func (f frame) py(y int) int {
+	return height - marginBottom - (y-f.yMin)*(height-marginTop-marginBottom)/(f.yMax-f.yMin)
+}
+
+func (f frame) yTicks() []int {
+	var ticks []int
+	for y := f.yMin; y <= f.yMax; y += f.yStep {
+		ticks = append(ticks, y)
+	}
+	return ticks
+}
+
+// xTicks are X values labeled, every one while there are few of them.
+func xTicks(xs []int) []int {
+	every := (len(xs) + maxXTicks - 1) / maxXTicks
+	var ticks []int
+	for i := 0; i < len(xs); i += every {
+		ticks = append(ticks, xs[i])
+	}
+	return ticks
+}
+
+// label formats y with thousands separated by spaces.
+func label(y int) string {
+	s := strconv.Itoa(abs(y))
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + " " + s[i:]
+	}
+	if y < 0 {
+		s = "-" + s
+	}
+	return s
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
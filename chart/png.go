@@ -0,0 +1,75 @@
+package chart
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"strconv"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+var (
+	lineColor = color.RGBA{0x1f, 0x77, 0xb4, 0xff}
+	gridColor = color.RGBA{0xe0, 0xe0, 0xe0, 0xff}
+)
+
+// WritePNG draws l as a PNG image.
+func WritePNG(w io.Writer, l Line) error {
+	if len(l.X) == 0 || len(l.X) != len(l.Y) {
+		return ErrNoData
+	}
+	f := newFrame(l)
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+
+	text(img, l.Title, width/2, 24, 0.5)
+	for _, y := range f.yTicks() {
+		segment(img, marginLeft, f.py(y), width-marginRight, f.py(y), 1, gridColor)
+		text(img, label(y), marginLeft-8, f.py(y)+4, 1)
+	}
+	for _, x := range xTicks(l.X) {
+		text(img, strconv.Itoa(x), f.px(x), height-marginBottom+18, 0.5)
+	}
+	segment(img, marginLeft, marginTop, marginLeft, height-marginBottom, 1, color.Black)
+	segment(img, marginLeft, height-marginBottom, width-marginRight, height-marginBottom, 1, color.Black)
+	text(img, l.XLabel, (marginLeft+width-marginRight)/2, height-12, 0.5)
+	text(img, l.YLabel, 8, marginTop-12, 0)
+
+	for i := 1; i < len(l.X); i++ {
+		segment(img, f.px(l.X[i-1]), f.py(l.Y[i-1]), f.px(l.X[i]), f.py(l.Y[i]), 2, lineColor)
+	}
+	for i := range l.X {
+		dot := image.Rect(f.px(l.X[i])-3, f.py(l.Y[i])-3, f.px(l.X[i])+4, f.py(l.Y[i])+4)
+		draw.Draw(img, dot, image.NewUniform(lineColor), image.Point{}, draw.Src)
+	}
+
+	return png.Encode(w, img)
+}
+
+// segment draws a line of thickness pixels from (x0, y0) to (x1, y1).
+func segment(img *image.RGBA, x0, y0, x1, y1, thickness int, c color.Color) {
+	steps := max(abs(x1-x0), abs(y1-y0), 1)
+	for i := 0; i <= steps; i++ {
+		x := x0 + (x1-x0)*i/steps
+		y := y0 + (y1-y0)*i/steps
+		for dx := range thickness {
+			for dy := range thickness {
+				img.Set(x+dx-thickness/2, y+dy-thickness/2, c)
+			}
+		}
+	}
+}
+
+// text draws s with baseline at y, anchored at x: 0 is left, 0.5 center and
+// 1 right.
+func text(img *image.RGBA, s string, x, y int, anchor float64) {
+	d := &font.Drawer{Dst: img, Src: image.Black, Face: basicfont.Face7x13}
+	x -= int(float64(d.MeasureString(s).Ceil()) * anchor)
+	d.Dot = fixed.P(x, y)
+	d.DrawString(s)
+}
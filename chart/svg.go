@@ -0,0 +1,51 @@
+package chart
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const lineColorHex = "#1f77b4"
+
+var ErrNoData = errors.New("nothing to chart")
+
+// WriteSVG draws l as an SVG image.
+func WriteSVG(w io.Writer, l Line) error {
+	if len(l.X) == 0 || len(l.X) != len(l.Y) {
+		return ErrNoData
+	}
+	f := newFrame(l)
+	out := bufio.NewWriter(w)
+
+	fmt.Fprintf(out, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="12">`+"\n", width, height, width, height)
+	fmt.Fprintf(out, `<rect width="%d" height="%d" fill="white"/>`+"\n", width, height)
+	fmt.Fprintf(out, `<text x="%d" y="24" text-anchor="middle" font-size="16">%s</text>`+"\n", width/2, html.EscapeString(l.Title))
+
+	for _, y := range f.yTicks() {
+		fmt.Fprintf(out, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#e0e0e0"/>`+"\n", marginLeft, f.py(y), width-marginRight, f.py(y))
+		fmt.Fprintf(out, `<text x="%d" y="%d" text-anchor="end">%s</text>`+"\n", marginLeft-8, f.py(y)+4, label(y))
+	}
+	for _, x := range xTicks(l.X) {
+		fmt.Fprintf(out, `<text x="%d" y="%d" text-anchor="middle">%d</text>`+"\n", f.px(x), height-marginBottom+18, x)
+	}
+	fmt.Fprintf(out, `<path d="M%d %dV%dH%d" fill="none" stroke="black"/>`+"\n", marginLeft, marginTop, height-marginBottom, width-marginRight)
+	fmt.Fprintf(out, `<text x="%d" y="%d" text-anchor="middle">%s</text>`+"\n", (marginLeft+width-marginRight)/2, height-12, html.EscapeString(l.XLabel))
+	fmt.Fprintf(out, `<text x="%d" y="%d">%s</text>`+"\n", 8, marginTop-12, html.EscapeString(l.YLabel))
+
+	points := make([]string, len(l.X))
+	for i := range l.X {
+		points[i] = strconv.Itoa(f.px(l.X[i])) + "," + strconv.Itoa(f.py(l.Y[i]))
+	}
+	fmt.Fprintf(out, `<polyline points="%s" fill="none" stroke="%s" stroke-width="2"/>`+"\n", strings.Join(points, " "), lineColorHex)
+	for i := range l.X {
+		fmt.Fprintf(out, `<circle cx="%d" cy="%d" r="3" fill="%s"><title>%d: %s</title></circle>`+"\n", f.px(l.X[i]), f.py(l.Y[i]), lineColorHex, l.X[i], label(l.Y[i]))
+	}
+
+	fmt.Fprintln(out, `</svg>`)
+	return out.Flush()
+}
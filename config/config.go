@@ -1,30 +1,547 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"net/url"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/joho/godotenv"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/blobs"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/domain"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/notify"
 )
 
+// FieldError is a validation problem of a single setting.
+type FieldError struct {
+	Field string
+	Msg   string
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + " " + e.Msg
+}
+
+func fieldErr(field, format string, args ...any) error {
+	return &FieldError{Field: field, Msg: fmt.Sprintf(format, args...)}
+}
+
 type Config struct {
-	Env                   string
-	Port				  string
-	ProfileServiceAddr    string
-	PredictionServiceAddr string
-	FeedServiceAddr       string
+	Env                   string        `yaml:"mode" toml:"mode"`
+	Port                  string        `yaml:"serve_port" toml:"serve_port"`
+	LogLevel              string        `yaml:"log_level" toml:"log_level"`
+	RequestTimeout        time.Duration `yaml:"request_timeout" toml:"request_timeout"`
+	RateLimit             float64       `yaml:"rate_limit" toml:"rate_limit"`
+	RateBurst             int           `yaml:"rate_burst" toml:"rate_burst"`
+	TrustedProxies        string        `yaml:"trusted_proxies" toml:"trusted_proxies"`
+	ScanLimit             int           `yaml:"scan_limit" toml:"scan_limit"`
+	CursorSecret          string        `yaml:"cursor_secret" toml:"cursor_secret"`
+	FieldPresets          string        `yaml:"field_presets" toml:"field_presets"`
+	BulkConcurrency       int           `yaml:"bulk_concurrency" toml:"bulk_concurrency"`
+	BulkAsyncRows         int           `yaml:"bulk_async_rows" toml:"bulk_async_rows"`
+	BulkMaxRows           int           `yaml:"bulk_max_rows" toml:"bulk_max_rows"`
+	ExportMaxRows         int           `yaml:"export_max_rows" toml:"export_max_rows"`
+	StoreDir              string        `yaml:"store_dir" toml:"store_dir"`
+	AccessTokenSecret     string        `yaml:"access_token_secret" toml:"access_token_secret"`
+	SavedSearchInterval   time.Duration `yaml:"saved_search_interval" toml:"saved_search_interval"`
+	NotifySinks           string        `yaml:"notify_sinks" toml:"notify_sinks"`
+	NotifyWebhookURL      string        `yaml:"notify_webhook_url" toml:"notify_webhook_url"`
+	NotifyWebhookSecret   string        `yaml:"notify_webhook_secret" toml:"notify_webhook_secret"`
+	NotifyFile            string        `yaml:"notify_file" toml:"notify_file"`
+	WatchInterval         time.Duration `yaml:"watch_interval" toml:"watch_interval"`
+	ListingEventsBuffer   int           `yaml:"listing_events_buffer" toml:"listing_events_buffer"`
+	PredictionWsDebounce  time.Duration `yaml:"prediction_ws_debounce" toml:"prediction_ws_debounce"`
+	PredictionWsRate      float64       `yaml:"prediction_ws_rate" toml:"prediction_ws_rate"`
+	PredictionWsOrigins   string        `yaml:"prediction_ws_origins" toml:"prediction_ws_origins"`
+	ValuationGoodDeal     float64       `yaml:"valuation_good_deal" toml:"valuation_good_deal"`
+	ValuationOverpriced   float64       `yaml:"valuation_overpriced" toml:"valuation_overpriced"`
+	ValuationConcurrency  int           `yaml:"valuation_concurrency" toml:"valuation_concurrency"`
+	ValuationBudget       time.Duration `yaml:"valuation_budget" toml:"valuation_budget"`
+	BatchConcurrency      int           `yaml:"batch_concurrency" toml:"batch_concurrency"`
+	BatchAsyncItems       int           `yaml:"batch_async_items" toml:"batch_async_items"`
+	BatchMaxItems         int           `yaml:"batch_max_items" toml:"batch_max_items"`
+	GraphTTL              time.Duration `yaml:"graph_ttl" toml:"graph_ttl"`
+	BlobStore             string        `yaml:"blob_store" toml:"blob_store"`
+	BlobDir               string        `yaml:"blob_dir" toml:"blob_dir"`
+	BlobMemoryMB          int           `yaml:"blob_memory_mb" toml:"blob_memory_mb"`
+	BlobSigningSecret     string        `yaml:"blob_signing_secret" toml:"blob_signing_secret"`
+	S3Endpoint            string        `yaml:"s3_endpoint" toml:"s3_endpoint"`
+	S3Bucket              string        `yaml:"s3_bucket" toml:"s3_bucket"`
+	S3Region              string        `yaml:"s3_region" toml:"s3_region"`
+	S3AccessKey           string        `yaml:"s3_access_key" toml:"s3_access_key"`
+	S3SecretKey           string        `yaml:"s3_secret_key" toml:"s3_secret_key"`
+	S3UseSSL              bool          `yaml:"s3_use_ssl" toml:"s3_use_ssl"`
+	ExportLinkTTL         time.Duration `yaml:"export_link_ttl" toml:"export_link_ttl"`
+	ImageProxyHosts       string        `yaml:"image_proxy_hosts" toml:"image_proxy_hosts"`
+	ImageCacheDir         string        `yaml:"image_cache_dir" toml:"image_cache_dir"`
+	ImageCacheTTL         time.Duration `yaml:"image_cache_ttl" toml:"image_cache_ttl"`
+	ImageCacheMaxMB       int           `yaml:"image_cache_max_mb" toml:"image_cache_max_mb"`
+	ProfileServiceAddr    string        `yaml:"profile_service_addr" toml:"profile_service_addr"`
+	PredictionServiceAddr string        `yaml:"prediction_service_addr" toml:"prediction_service_addr"`
+	FeedServiceAddr       string        `yaml:"feed_service_addr" toml:"feed_service_addr"`
+	TranscodeEnabled      bool          `yaml:"transcode_enabled" toml:"transcode_enabled"`
+	TranscodeRoutes       string        `yaml:"transcode_routes" toml:"transcode_routes"`
+	RpcEnabled            bool          `yaml:"rpc_enabled" toml:"rpc_enabled"`
+	RpcAllowlist          string        `yaml:"rpc_allowlist" toml:"rpc_allowlist"`
+	RpcDescriptorSets     string        `yaml:"rpc_descriptor_sets" toml:"rpc_descriptor_sets"`
 }
 
-func Load(envfile string) (*Config, error) {
-	if err := godotenv.Load(envfile); err != nil {
-		return nil, fmt.Errorf("failed to load .env file: %v", err)
+func Default() *Config {
+	return &Config{
+		Env:             "local",
+		Port:            "4242",
+		RequestTimeout:  30 * time.Second,
+		RateBurst:       20,
+		ScanLimit:       1000,
+		FieldPresets:    "card=listing_id,price,make,model_name,year,mileage,body_type,posted_at; detail=*",
+		BulkConcurrency: 8,
+		BulkAsyncRows:   100,
+		BulkMaxRows:     10000,
+		ExportMaxRows:   50000,
+
+		SavedSearchInterval: 5 * time.Minute,
+		WatchInterval:       time.Minute,
+		ListingEventsBuffer: 1000,
+		NotifySinks:         "log,inbox",
+
+		PredictionWsDebounce: 300 * time.Millisecond,
+		PredictionWsRate:     10,
+
+		ValuationGoodDeal:    10,
+		ValuationOverpriced:  10,
+		ValuationConcurrency: 8,
+		ValuationBudget:      2 * time.Second,
+
+		BatchConcurrency: 4,
+		BatchAsyncItems:  50,
+		BatchMaxItems:    1000,
+		GraphTTL:         30 * time.Minute,
+
+		BlobStore:     "memory",
+		BlobMemoryMB:  256,
+		S3UseSSL:      true,
+		ExportLinkTTL: time.Hour,
+
+		ImageCacheDir:   filepath.Join(os.TempDir(), "car-estimator-images"),
+		ImageCacheTTL:   7 * 24 * time.Hour,
+		ImageCacheMaxMB: 1024,
 	}
+}
 
-	return &Config{
-		Env: os.Getenv("MODE"),
-		Port: os.Getenv("SERVE_PORT"),
-		ProfileServiceAddr: os.Getenv("PROFILE_SERVICE_ADDR"),
-		PredictionServiceAddr: os.Getenv("PREDICTION_SERVICE_ADDR"),
-		FeedServiceAddr: os.Getenv("FEED_SERVICE_ADDR"),
-	}, nil
+// Level returns configured log level, falling back to mode default.
+func (c *Config) Level() (slog.Level, error) {
+	var level slog.Level
+	if c.LogLevel == "" {
+		if c.Env == "local" {
+			return slog.LevelDebug, nil
+		}
+		return slog.LevelInfo, nil
+	}
+	err := level.UnmarshalText([]byte(c.LogLevel))
+	return level, err
+}
+
+func (c *Config) Validate() error {
+	var errs []error
+
+	switch c.Env {
+	case "local", "production":
+	case "":
+		errs = append(errs, fieldErr("MODE", "is required (local or production)"))
+	default:
+		errs = append(errs, fieldErr("MODE", "%q is unknown, expected local or production", c.Env))
+	}
+
+	if c.Port == "" {
+		errs = append(errs, fieldErr("SERVE_PORT", "is required"))
+	} else if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+		errs = append(errs, fieldErr("SERVE_PORT", "%q must be a number between 1 and 65535", c.Port))
+	}
+
+	if _, err := c.Level(); err != nil {
+		errs = append(errs, fieldErr("LOG_LEVEL", "%q is unknown, expected debug, info, warn or error", c.LogLevel))
+	}
+
+	if c.RequestTimeout < 0 {
+		errs = append(errs, fieldErr("REQUEST_TIMEOUT", "%s can't be negative", c.RequestTimeout))
+	}
+	if c.RateLimit < 0 {
+		errs = append(errs, fieldErr("RATE_LIMIT", "%v can't be negative", c.RateLimit))
+	}
+	if c.RateLimit > 0 && c.RateBurst < 1 {
+		errs = append(errs, fieldErr("RATE_BURST", "%d must be positive when RATE_LIMIT is set", c.RateBurst))
+	}
+	if _, err := c.TrustedProxyList(); err != nil {
+		errs = append(errs, fieldErr("TRUSTED_PROXIES", "%v", err))
+	}
+
+	if c.ScanLimit < 1 {
+		errs = append(errs, fieldErr("SCAN_LIMIT", "%d must be positive", c.ScanLimit))
+	}
+
+	if c.BulkConcurrency < 1 {
+		errs = append(errs, fieldErr("BULK_CONCURRENCY", "%d must be positive", c.BulkConcurrency))
+	}
+	if c.BulkAsyncRows < 0 {
+		errs = append(errs, fieldErr("BULK_ASYNC_ROWS", "%d can't be negative", c.BulkAsyncRows))
+	}
+	if c.BulkMaxRows < 1 {
+		errs = append(errs, fieldErr("BULK_MAX_ROWS", "%d must be positive", c.BulkMaxRows))
+	}
+
+	if c.ExportMaxRows < 1 {
+		errs = append(errs, fieldErr("EXPORT_MAX_ROWS", "%d must be positive", c.ExportMaxRows))
+	}
+
+	if c.SavedSearchInterval < 0 {
+		errs = append(errs, fieldErr("SAVED_SEARCH_INTERVAL", "%s can't be negative", c.SavedSearchInterval))
+	}
+	if c.WatchInterval < 0 {
+		errs = append(errs, fieldErr("WATCH_INTERVAL", "%s can't be negative", c.WatchInterval))
+	}
+	if c.ListingEventsBuffer < 1 {
+		errs = append(errs, fieldErr("LISTING_EVENTS_BUFFER", "%d must be positive", c.ListingEventsBuffer))
+	}
+
+	if c.PredictionWsDebounce < 0 {
+		errs = append(errs, fieldErr("PREDICTION_WS_DEBOUNCE", "%s can't be negative", c.PredictionWsDebounce))
+	}
+	if c.PredictionWsRate < 0 {
+		errs = append(errs, fieldErr("PREDICTION_WS_RATE", "%v can't be negative", c.PredictionWsRate))
+	}
+
+	if c.ValuationGoodDeal < 0 || c.ValuationGoodDeal >= 100 {
+		errs = append(errs, fieldErr("VALUATION_GOOD_DEAL", "%v must be a percent from 0 to 100", c.ValuationGoodDeal))
+	}
+	if c.ValuationOverpriced < 0 {
+		errs = append(errs, fieldErr("VALUATION_OVERPRICED", "%v can't be negative", c.ValuationOverpriced))
+	}
+	if c.ValuationConcurrency < 1 {
+		errs = append(errs, fieldErr("VALUATION_CONCURRENCY", "%d must be positive", c.ValuationConcurrency))
+	}
+	if c.ValuationBudget <= 0 {
+		errs = append(errs, fieldErr("VALUATION_BUDGET", "%s must be positive", c.ValuationBudget))
+	}
+	if c.BatchConcurrency < 1 {
+		errs = append(errs, fieldErr("BATCH_CONCURRENCY", "%d must be positive", c.BatchConcurrency))
+	}
+	if c.BatchAsyncItems < 0 {
+		errs = append(errs, fieldErr("BATCH_ASYNC_ITEMS", "%d can't be negative", c.BatchAsyncItems))
+	}
+	if c.BatchMaxItems < 1 {
+		errs = append(errs, fieldErr("BATCH_MAX_ITEMS", "%d must be positive", c.BatchMaxItems))
+	}
+	if c.GraphTTL <= 0 {
+		errs = append(errs, fieldErr("GRAPH_TTL", "%s must be positive", c.GraphTTL))
+	}
+	switch c.BlobStore {
+	case "fs":
+		if c.BlobDir == "" {
+			errs = append(errs, fieldErr("BLOB_DIR", "must be set for fs blob store"))
+		}
+	case "memory":
+		if c.BlobMemoryMB < 1 {
+			errs = append(errs, fieldErr("BLOB_MEMORY_MB", "%d must be positive", c.BlobMemoryMB))
+		}
+	case "s3":
+		if c.S3Endpoint == "" {
+			errs = append(errs, fieldErr("S3_ENDPOINT", "must be set for s3 blob store"))
+		}
+		if c.S3Bucket == "" {
+			errs = append(errs, fieldErr("S3_BUCKET", "must be set for s3 blob store"))
+		}
+	default:
+		if !slices.Contains(blobs.Kinds, c.BlobStore) {
+			errs = append(errs, fieldErr("BLOB_STORE", "%q is unknown, expected %s", c.BlobStore, strings.Join(blobs.Kinds, ", ")))
+		}
+	}
+	if c.ExportLinkTTL <= 0 {
+		errs = append(errs, fieldErr("EXPORT_LINK_TTL", "%s must be positive", c.ExportLinkTTL))
+	}
+	for _, host := range c.ImageProxyHostList() {
+		if strings.ContainsAny(host, ":/ ") {
+			errs = append(errs, fieldErr("IMAGE_PROXY_HOSTS", "%q isn't a host name", host))
+		}
+	}
+	if c.ImageCacheTTL <= 0 {
+		errs = append(errs, fieldErr("IMAGE_CACHE_TTL", "%s must be positive", c.ImageCacheTTL))
+	}
+	if c.ImageCacheMaxMB < 1 {
+		errs = append(errs, fieldErr("IMAGE_CACHE_MAX_MB", "%d must be positive", c.ImageCacheMaxMB))
+	}
+	for _, name := range strings.Split(c.NotifySinks, ",") {
+		name = strings.TrimSpace(name)
+		if name != "" && !slices.Contains(notify.SinkNames, name) {
+			errs = append(errs, fieldErr("NOTIFY_SINKS", "%q is unknown, expected %s", name, strings.Join(notify.SinkNames, ", ")))
+		}
+		if name == "webhook" && c.NotifyWebhookURL == "" {
+			errs = append(errs, fieldErr("NOTIFY_WEBHOOK_URL", "must be set for webhook sink"))
+		}
+		if name == "file" && c.NotifyFile == "" {
+			errs = append(errs, fieldErr("NOTIFY_FILE", "must be set for file sink"))
+		}
+	}
+	if c.NotifyWebhookURL != "" {
+		if u, err := url.Parse(c.NotifyWebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fieldErr("NOTIFY_WEBHOOK_URL", "%q isn't an http(s) URL", c.NotifyWebhookURL))
+		}
+	}
+
+	if _, err := c.ListingFieldPresets(); err != nil {
+		errs = append(errs, fieldErr("FIELD_PRESETS", "%v", err))
+	}
+
+	for _, backend := range []string{"profile", "prediction", "feed"} {
+		env := strings.ToUpper(backend) + "_SERVICE_ADDR"
+		addr := c.Backends()[backend]
+		if addr == "" {
+			errs = append(errs, fieldErr(env, "is required"))
+		} else if err := ValidateAddr(addr); err != nil {
+			errs = append(errs, fieldErr(env, "%q is not a valid gRPC target: %v", addr, err))
+		}
+	}
+
+	if _, err := c.DescriptorSets(); err != nil {
+		errs = append(errs, fieldErr("RPC_DESCRIPTOR_SETS", "%v", err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Backends maps backend names to their gRPC addresses.
+func (c *Config) Backends() map[string]string {
+	return map[string]string{
+		"profile":    c.ProfileServiceAddr,
+		"prediction": c.PredictionServiceAddr,
+		"feed":       c.FeedServiceAddr,
+	}
+}
+
+// DescriptorSets parses RPC_DESCRIPTOR_SETS in form "backend=path,backend=path".
+func (c *Config) DescriptorSets() (map[string]string, error) {
+	sets := map[string]string{}
+	backends := c.Backends()
+
+	for _, entry := range strings.Split(c.RpcDescriptorSets, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		backend, path, ok := strings.Cut(entry, "=")
+		if !ok || path == "" {
+			return nil, fmt.Errorf("entry %q must look like backend=path", entry)
+		}
+		if _, known := backends[backend]; !known {
+			return nil, fmt.Errorf("unknown backend %q in entry %q", backend, entry)
+		}
+		sets[backend] = path
+	}
+
+	return sets, nil
+}
+
+// FieldPresetRoutes are feed read endpoints presets can be defined for separately.
+var FieldPresetRoutes = []string{"list", "search", "get", "favorites"}
+
+// ListingFieldPresets parses FIELD_PRESETS in form "card=price,year; search.card=price;
+// detail=*", mapping route to preset name to its fields. Presets without route
+// prefix are stored under "" and apply to every route, "*" selects all fields.
+func (c *Config) ListingFieldPresets() (map[string]map[string][]string, error) {
+	presets := map[string]map[string][]string{}
+
+	for _, entry := range strings.Split(c.FieldPresets, ";") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		name, list, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(list) == "" {
+			return nil, fmt.Errorf("entry %q must look like [route.]name=field,field", entry)
+		}
+
+		route, name, scoped := strings.Cut(strings.TrimSpace(name), ".")
+		if !scoped {
+			route, name = "", route
+		} else if !slices.Contains(FieldPresetRoutes, route) {
+			return nil, fmt.Errorf("unknown route %q in entry %q, expected one of %s", route, entry, strings.Join(FieldPresetRoutes, ", "))
+		}
+		if name == "" || domain.IsListingField(name) {
+			return nil, fmt.Errorf("preset name %q in entry %q is empty or clashes with a listing field", name, entry)
+		}
+
+		var fields []string
+		for _, f := range strings.Split(list, ",") {
+			switch f = strings.TrimSpace(f); {
+			case f == "*":
+				fields = append(fields, domain.ListingFields...)
+			case domain.IsListingField(f):
+				fields = append(fields, f)
+			case f != "":
+				return nil, fmt.Errorf("unknown listing field %q in entry %q", f, entry)
+			}
+		}
+
+		if presets[route] == nil {
+			presets[route] = map[string][]string{}
+		}
+		presets[route][name] = fields
+	}
+
+	return presets, nil
+}
+
+// ValidateAddr checks gRPC target: host:port or scheme:///endpoint form.
+func ValidateAddr(addr string) error {
+	if scheme, rest, ok := strings.Cut(addr, ":"); ok && (scheme == "unix" || scheme == "unix-abstract") {
+		if strings.TrimPrefix(rest, "//") == "" {
+			return errors.New("socket path is empty")
+		}
+		return nil
+	}
+
+	if strings.Contains(addr, "://") {
+		u, err := url.Parse(addr)
+		if err != nil {
+			return err
+		}
+		addr = strings.TrimPrefix(u.Path, "/")
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("port %q is invalid", port)
+	}
+	if host != "" && strings.ContainsAny(host, " /") {
+		return fmt.Errorf("host %q is invalid", host)
+	}
+	return nil
+}
+
+// RestartRequired lists changed settings which can't be applied to a running server.
+func RestartRequired(old, new *Config) []string {
+	var changed []string
+
+	if old.Env != new.Env {
+		changed = append(changed, "MODE")
+	}
+	if old.Port != new.Port {
+		changed = append(changed, "SERVE_PORT")
+	}
+	if old.TranscodeEnabled != new.TranscodeEnabled || old.TranscodeRoutes != new.TranscodeRoutes {
+		changed = append(changed, "TRANSCODE_*")
+	}
+	if old.RpcEnabled != new.RpcEnabled || old.RpcAllowlist != new.RpcAllowlist || old.RpcDescriptorSets != new.RpcDescriptorSets {
+		changed = append(changed, "RPC_*")
+	}
+	if old.CursorSecret != new.CursorSecret {
+		changed = append(changed, "CURSOR_SECRET")
+	}
+	if old.FieldPresets != new.FieldPresets {
+		changed = append(changed, "FIELD_PRESETS")
+	}
+	if old.BulkConcurrency != new.BulkConcurrency || old.BulkAsyncRows != new.BulkAsyncRows || old.BulkMaxRows != new.BulkMaxRows {
+		changed = append(changed, "BULK_*")
+	}
+	if old.ExportMaxRows != new.ExportMaxRows {
+		changed = append(changed, "EXPORT_MAX_ROWS")
+	}
+	if old.StoreDir != new.StoreDir {
+		changed = append(changed, "STORE_DIR")
+	}
+	if old.AccessTokenSecret != new.AccessTokenSecret {
+		changed = append(changed, "ACCESS_TOKEN_SECRET")
+	}
+	if old.SavedSearchInterval != new.SavedSearchInterval {
+		changed = append(changed, "SAVED_SEARCH_INTERVAL")
+	}
+	if old.WatchInterval != new.WatchInterval {
+		changed = append(changed, "WATCH_INTERVAL")
+	}
+	if old.ListingEventsBuffer != new.ListingEventsBuffer {
+		changed = append(changed, "LISTING_EVENTS_BUFFER")
+	}
+	if old.PredictionWsDebounce != new.PredictionWsDebounce || old.PredictionWsRate != new.PredictionWsRate || old.PredictionWsOrigins != new.PredictionWsOrigins {
+		changed = append(changed, "PREDICTION_WS_*")
+	}
+	if old.ValuationGoodDeal != new.ValuationGoodDeal || old.ValuationOverpriced != new.ValuationOverpriced ||
+		old.ValuationConcurrency != new.ValuationConcurrency || old.ValuationBudget != new.ValuationBudget {
+		changed = append(changed, "VALUATION_*")
+	}
+	if old.BatchConcurrency != new.BatchConcurrency || old.BatchAsyncItems != new.BatchAsyncItems || old.BatchMaxItems != new.BatchMaxItems {
+		changed = append(changed, "BATCH_*")
+	}
+	if old.GraphTTL != new.GraphTTL {
+		changed = append(changed, "GRAPH_TTL")
+	}
+	if old.BlobStore != new.BlobStore || old.BlobDir != new.BlobDir || old.BlobMemoryMB != new.BlobMemoryMB || old.BlobSigningSecret != new.BlobSigningSecret {
+		changed = append(changed, "BLOB_*")
+	}
+	if old.S3Endpoint != new.S3Endpoint || old.S3Bucket != new.S3Bucket || old.S3Region != new.S3Region ||
+		old.S3AccessKey != new.S3AccessKey || old.S3SecretKey != new.S3SecretKey || old.S3UseSSL != new.S3UseSSL {
+		changed = append(changed, "S3_*")
+	}
+	if old.ExportLinkTTL != new.ExportLinkTTL {
+		changed = append(changed, "EXPORT_LINK_TTL")
+	}
+	if old.ImageProxyHosts != new.ImageProxyHosts || old.ImageCacheDir != new.ImageCacheDir || old.ImageCacheTTL != new.ImageCacheTTL ||
+		old.ImageCacheMaxMB != new.ImageCacheMaxMB {
+		changed = append(changed, "IMAGE_*")
+	}
+	if old.NotifySinks != new.NotifySinks || old.NotifyWebhookURL != new.NotifyWebhookURL ||
+		old.NotifyWebhookSecret != new.NotifyWebhookSecret || old.NotifyFile != new.NotifyFile {
+		changed = append(changed, "NOTIFY_*")
+	}
+
+	return changed
+}
+
+// PredictionWsOriginList splits PREDICTION_WS_ORIGINS.
+func (c *Config) PredictionWsOriginList() []string {
+	var origins []string
+	for _, o := range strings.Split(c.PredictionWsOrigins, ",") {
+		if o = strings.TrimSpace(o); o != "" {
+			origins = append(origins, o)
+		}
+	}
+	return origins
+}
+
+// TrustedProxyList parses TRUSTED_PROXIES, whose entries are IP addresses or
+// CIDR ranges.
+func (c *Config) TrustedProxyList() ([]netip.Prefix, error) {
+	var proxies []netip.Prefix
+	for _, entry := range strings.Split(c.TrustedProxies, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		if addr, err := netip.ParseAddr(entry); err == nil {
+			proxies = append(proxies, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%q is neither an IP address nor a CIDR range", entry)
+		}
+		proxies = append(proxies, prefix.Masked())
+	}
+	return proxies, nil
+}
+
+// ImageProxyHostList returns lowercased hosts of IMAGE_PROXY_HOSTS.
+func (c *Config) ImageProxyHostList() []string {
+	var hosts []string
+	for _, h := range strings.Split(c.ImageProxyHosts, ",") {
+		if h = strings.ToLower(strings.TrimSpace(h)); h != "" {
+			hosts = append(hosts, h)
+		}
+	}
+	return hosts
 }
@@ -0,0 +1,267 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+type field struct {
+	env   string
+	usage string
+	set   func(c *Config, v string) error
+	get   func(c *Config) string
+}
+
+func (f field) flagName() string {
+	return strings.ReplaceAll(strings.ToLower(f.env), "_", "-")
+}
+
+func stringField(env, usage string, get func(*Config) *string) field {
+	return field{env, usage, func(c *Config, v string) error {
+		*get(c) = v
+		return nil
+	}, func(c *Config) string {
+		return *get(c)
+	}}
+}
+
+// secretField is a string setting which is masked in diagnostics output.
+func secretField(env, usage string, get func(*Config) *string) field {
+	f := stringField(env, usage, get)
+	f.get = func(c *Config) string {
+		if *get(c) == "" {
+			return ""
+		}
+		return "***"
+	}
+	return f
+}
+
+func boolField(env, usage string, get func(*Config) *bool) field {
+	return field{env, usage, func(c *Config, v string) (err error) {
+		*get(c), err = strconv.ParseBool(v)
+		return
+	}, func(c *Config) string {
+		return fmt.Sprint(*get(c))
+	}}
+}
+
+func intField(env, usage string, get func(*Config) *int) field {
+	return field{env, usage, func(c *Config, v string) (err error) {
+		*get(c), err = strconv.Atoi(v)
+		return
+	}, func(c *Config) string {
+		return fmt.Sprint(*get(c))
+	}}
+}
+
+func floatField(env, usage string, get func(*Config) *float64) field {
+	return field{env, usage, func(c *Config, v string) (err error) {
+		*get(c), err = strconv.ParseFloat(v, 64)
+		return
+	}, func(c *Config) string {
+		return fmt.Sprint(*get(c))
+	}}
+}
+
+func durationField(env, usage string, get func(*Config) *time.Duration) field {
+	return field{env, usage, func(c *Config, v string) (err error) {
+		*get(c), err = time.ParseDuration(v)
+		return
+	}, func(c *Config) string {
+		return fmt.Sprint(*get(c))
+	}}
+}
+
+var fields = []field{
+	stringField("MODE", "environment: local or production", func(c *Config) *string { return &c.Env }),
+	stringField("SERVE_PORT", "HTTP port to listen on", func(c *Config) *string { return &c.Port }),
+	stringField("LOG_LEVEL", "debug, info, warn or error (default depends on mode)", func(c *Config) *string { return &c.LogLevel }),
+	durationField("REQUEST_TIMEOUT", "per-request deadline, 0 disables it", func(c *Config) *time.Duration { return &c.RequestTimeout }),
+	floatField("RATE_LIMIT", "requests per second allowed for a client, 0 disables limiting", func(c *Config) *float64 { return &c.RateLimit }),
+	intField("RATE_BURST", "request burst allowed for a client", func(c *Config) *int { return &c.RateBurst }),
+	stringField("TRUSTED_PROXIES", "comma separated IPs or CIDR ranges of proxies whose X-Forwarded-For tells the client of rate limiting", func(c *Config) *string { return &c.TrustedProxies }),
+	intField("SCAN_LIMIT", "max listings scanned when the gateway filters or aggregates feed results", func(c *Config) *int { return &c.ScanLimit }),
+	secretField("CURSOR_SECRET", "key signing pagination cursors, random per process if empty", func(c *Config) *string { return &c.CursorSecret }),
+	stringField("FIELD_PRESETS", "named listing field sets for fields= param, [route.]name=field,field entries separated by ;", func(c *Config) *string { return &c.FieldPresets }),
+	intField("BULK_CONCURRENCY", "parallel CreateListing calls of a bulk import", func(c *Config) *int { return &c.BulkConcurrency }),
+	intField("BULK_ASYNC_ROWS", "bulk imports with more rows run as background jobs", func(c *Config) *int { return &c.BulkAsyncRows }),
+	intField("BULK_MAX_ROWS", "max rows of a bulk import", func(c *Config) *int { return &c.BulkMaxRows }),
+	intField("EXPORT_MAX_ROWS", "max rows of a listing export", func(c *Config) *int { return &c.ExportMaxRows }),
+	stringField("STORE_DIR", "directory of gateway-side data like favorites, kept in memory if empty", func(c *Config) *string { return &c.StoreDir }),
+	durationField("SAVED_SEARCH_INTERVAL", "how often saved searches are re-run for new matches, 0 disables it", func(c *Config) *time.Duration { return &c.SavedSearchInterval }),
+	durationField("WATCH_INTERVAL", "how often watched listings are checked for price and status changes, 0 disables it", func(c *Config) *time.Duration { return &c.WatchInterval }),
+	intField("LISTING_EVENTS_BUFFER", "listing changes kept for /feed/listings/stream clients resuming with Last-Event-ID", func(c *Config) *int { return &c.ListingEventsBuffer }),
+	durationField("PREDICTION_WS_DEBOUNCE", "how long /prediction/ws waits for a newer request before predicting", func(c *Config) *time.Duration { return &c.PredictionWsDebounce }),
+	floatField("PREDICTION_WS_RATE", "requests per second allowed on a /prediction/ws connection, 0 disables limiting", func(c *Config) *float64 { return &c.PredictionWsRate }),
+	stringField("PREDICTION_WS_ORIGINS", "comma separated origins allowed to open /prediction/ws besides the gateway one, * allows any", func(c *Config) *string { return &c.PredictionWsOrigins }),
+	floatField("VALUATION_GOOD_DEAL", "percent below the predicted price a listing is rated a good deal from", func(c *Config) *float64 { return &c.ValuationGoodDeal }),
+	floatField("VALUATION_OVERPRICED", "percent above the predicted price a listing is rated overpriced from", func(c *Config) *float64 { return &c.ValuationOverpriced }),
+	intField("VALUATION_CONCURRENCY", "parallel Predict calls valuing a page of listings with_valuation=true", func(c *Config) *int { return &c.ValuationConcurrency }),
+	durationField("VALUATION_BUDGET", "time valuing a page of listings can take, listings not valued by then are returned without valuation", func(c *Config) *time.Duration { return &c.ValuationBudget }),
+	intField("BATCH_CONCURRENCY", "parallel Predict calls of a prediction batch", func(c *Config) *int { return &c.BatchConcurrency }),
+	intField("BATCH_ASYNC_ITEMS", "prediction batches with more items run as background jobs", func(c *Config) *int { return &c.BatchAsyncItems }),
+	intField("BATCH_MAX_ITEMS", "max items of a prediction batch", func(c *Config) *int { return &c.BatchMaxItems }),
+	durationField("GRAPH_TTL", "how long prediction graphs linked by graph_url are served for", func(c *Config) *time.Duration { return &c.GraphTTL }),
+	stringField("BLOB_STORE", "where prediction graphs and export links are kept: memory, fs or s3", func(c *Config) *string { return &c.BlobStore }),
+	stringField("BLOB_DIR", "root directory of fs blob store", func(c *Config) *string { return &c.BlobDir }),
+	intField("BLOB_MEMORY_MB", "megabytes memory blob store keeps, evicting blobs expiring soonest beyond it", func(c *Config) *int { return &c.BlobMemoryMB }),
+	secretField("BLOB_SIGNING_SECRET", "key signing /blobs URLs of memory and fs blob stores, random if empty", func(c *Config) *string { return &c.BlobSigningSecret }),
+	stringField("S3_ENDPOINT", "host:port of S3 compatible storage of s3 blob store", func(c *Config) *string { return &c.S3Endpoint }),
+	stringField("S3_BUCKET", "bucket of s3 blob store, expired blobs are left to its lifecycle rules", func(c *Config) *string { return &c.S3Bucket }),
+	stringField("S3_REGION", "region of S3_BUCKET, looked up if empty", func(c *Config) *string { return &c.S3Region }),
+	secretField("S3_ACCESS_KEY", "access key of s3 blob store", func(c *Config) *string { return &c.S3AccessKey }),
+	secretField("S3_SECRET_KEY", "secret key of s3 blob store", func(c *Config) *string { return &c.S3SecretKey }),
+	boolField("S3_USE_SSL", "connect to S3_ENDPOINT over HTTPS", func(c *Config) *bool { return &c.S3UseSSL }),
+	durationField("EXPORT_LINK_TTL", "how long exports made with delivery=link can be downloaded", func(c *Config) *time.Duration { return &c.ExportLinkTTL }),
+	stringField("IMAGE_PROXY_HOSTS", "comma separated hosts, with their subdomains, car photos are proxied and resized from, proxy is off if empty", func(c *Config) *string { return &c.ImageProxyHosts }),
+	stringField("IMAGE_CACHE_DIR", "directory proxied photos are cached in, not cached if empty", func(c *Config) *string { return &c.ImageCacheDir }),
+	durationField("IMAGE_CACHE_TTL", "how long proxied photos stay cached", func(c *Config) *time.Duration { return &c.ImageCacheTTL }),
+	intField("IMAGE_CACHE_MAX_MB", "megabytes of proxied photos cached, the oldest are removed beyond it", func(c *Config) *int { return &c.ImageCacheMaxMB }),
+	stringField("NOTIFY_SINKS", "comma separated notification sinks: log, inbox, webhook, sse, file", func(c *Config) *string { return &c.NotifySinks }),
+	stringField("NOTIFY_WEBHOOK_URL", "URL webhook sink POSTs notifications to", func(c *Config) *string { return &c.NotifyWebhookURL }),
+	secretField("NOTIFY_WEBHOOK_SECRET", "key signing webhook notifications with HMAC-SHA256, unsigned if empty", func(c *Config) *string { return &c.NotifyWebhookSecret }),
+	stringField("NOTIFY_FILE", "file sink appends notifications to, as NDJSON", func(c *Config) *string { return &c.NotifyFile }),
+	secretField("ACCESS_TOKEN_SECRET", "HS256 key of profile service access tokens, identifies users of /feed/users and watch routes; if empty their userId or user_id param is trusted", func(c *Config) *string { return &c.AccessTokenSecret }),
+	stringField("PROFILE_SERVICE_ADDR", "profile service gRPC address", func(c *Config) *string { return &c.ProfileServiceAddr }),
+	stringField("PREDICTION_SERVICE_ADDR", "prediction service gRPC address", func(c *Config) *string { return &c.PredictionServiceAddr }),
+	stringField("FEED_SERVICE_ADDR", "feed service gRPC address", func(c *Config) *string { return &c.FeedServiceAddr }),
+	boolField("TRANSCODE_ENABLED", "expose google.api.http annotated methods", func(c *Config) *bool { return &c.TranscodeEnabled }),
+	stringField("TRANSCODE_ROUTES", "explicit transcoding routes", func(c *Config) *string { return &c.TranscodeRoutes }),
+	boolField("RPC_ENABLED", "expose allowlisted methods under /rpc", func(c *Config) *bool { return &c.RpcEnabled }),
+	stringField("RPC_ALLOWLIST", "comma separated package.Service/Method list", func(c *Config) *string { return &c.RpcAllowlist }),
+	stringField("RPC_DESCRIPTOR_SETS", "backend=path descriptor sets used instead of reflection", func(c *Config) *string { return &c.RpcDescriptorSets }),
+}
+
+// Loader builds Config from layered sources, later ones overriding earlier:
+// defaults, config file (YAML or TOML), .env file, environment, CLI flags.
+type Loader struct {
+	ConfigFile string
+	EnvFile    string
+	flags      map[string]string
+}
+
+// NewLoader registers config flags in fset, so callers can add their own
+// flags to it, and parses args.
+func NewLoader(fset *flag.FlagSet, args []string) (*Loader, error) {
+	l := &Loader{flags: map[string]string{}}
+
+	fset.StringVar(&l.ConfigFile, "config", os.Getenv("CONFIG_FILE"), "path to YAML or TOML config file")
+	fset.StringVar(&l.EnvFile, "env-file", ".env", "path to optional .env file")
+	known := map[string]bool{}
+	for _, f := range fields {
+		fset.String(f.flagName(), "", f.usage)
+		known[f.flagName()] = true
+	}
+
+	if err := fset.Parse(args); err != nil {
+		return nil, err
+	}
+
+	fset.Visit(func(fl *flag.Flag) {
+		if known[fl.Name] {
+			l.flags[fl.Name] = fl.Value.String()
+		}
+	})
+
+	return l, nil
+}
+
+// Settings returns values of all settings by their environment names in
+// declaration order, for diagnostics output.
+func (c *Config) Settings() [][2]string {
+	settings := make([][2]string, 0, len(fields))
+	for _, f := range fields {
+		settings = append(settings, [2]string{f.env, f.get(c)})
+	}
+	return settings
+}
+
+// Load reads all sources again, so it is used for reloads as well. Config is
+// returned along with format errors, so they can be reported all together.
+func (l *Loader) Load() (*Config, error) {
+	c := Default()
+
+	if l.ConfigFile != "" {
+		if err := l.readFile(c); err != nil {
+			return nil, err
+		}
+	}
+
+	dotenv := map[string]string{}
+	if l.EnvFile != "" {
+		var err error
+		dotenv, err = godotenv.Read(l.EnvFile)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("failed to load %s file: %v", l.EnvFile, err)
+		}
+	}
+
+	var errs []error
+	for _, f := range fields {
+		value, ok := os.LookupEnv(f.env)
+		if !ok {
+			value, ok = dotenv[f.env]
+		}
+		if v, set := l.flags[f.flagName()]; set {
+			value, ok = v, true
+		}
+		if !ok {
+			continue
+		}
+		if err := f.set(c, value); err != nil {
+			errs = append(errs, fieldErr(f.env, "%q has wrong format: %v", value, err))
+		}
+	}
+
+	return c, errors.Join(errs...)
+}
+
+func (l *Loader) readFile(c *Config) error {
+	raw, err := os.ReadFile(l.ConfigFile)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %v", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(l.ConfigFile)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(raw, c)
+	case ".toml":
+		err = toml.Unmarshal(raw, c)
+	default:
+		return fmt.Errorf("config file %s must be .yaml, .yml or .toml", l.ConfigFile)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to parse config file %s: %v", l.ConfigFile, err)
+	}
+	return nil
+}
+
+// Load reads and validates config once, taking sources from command line args.
+func Load(args []string) (*Config, error) {
+	l, err := NewLoader(flag.NewFlagSet("gateway", flag.ContinueOnError), args)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := l.Load()
+	if err != nil {
+		return nil, err
+	}
+	if err = c.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%v", err)
+	}
+	return c, nil
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const reloadDebounce = 300 * time.Millisecond
+
+// Watcher reloads config on SIGHUP or config/.env file change and passes
+// valid results to subscribers. Invalid configs are logged and ignored.
+type Watcher struct {
+	loader  *Loader
+	logger  *slog.Logger
+	mu      sync.Mutex
+	current *Config
+	subs    []func(*Config)
+}
+
+func NewWatcher(loader *Loader, current *Config, logger *slog.Logger) *Watcher {
+	return &Watcher{
+		loader:  loader,
+		logger:  logger.With(slog.String("op", "config reload")),
+		current: current,
+	}
+}
+
+func (w *Watcher) OnReload(fn func(*Config)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subs = append(w.subs, fn)
+}
+
+func (w *Watcher) Current() *Config {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.current
+}
+
+func (w *Watcher) Reload() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	c, err := w.loader.Load()
+	if err == nil {
+		err = c.Validate()
+	}
+	if err != nil {
+		w.logger.Error("new configuration rejected, keeping the old one", slog.Any("error", err))
+		return
+	}
+
+	if changed := RestartRequired(w.current, c); len(changed) > 0 {
+		w.logger.Warn("some changed settings take effect only after restart", slog.Any("settings", changed))
+	}
+
+	w.current = c
+	for _, fn := range w.subs {
+		fn(c)
+	}
+	w.logger.Info("Configuration reloaded")
+}
+
+func (w *Watcher) Run(ctx context.Context) error {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer fw.Close()
+
+	// directories are watched since editors replace files instead of writing them
+	watched := map[string]bool{}
+	for _, path := range []string{w.loader.ConfigFile, w.loader.EnvFile} {
+		if path == "" {
+			continue
+		}
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			continue
+		}
+		watched[abs] = true
+		if err = fw.Add(filepath.Dir(abs)); err != nil {
+			w.logger.Warn("can't watch config file", slog.String("path", path), slog.Any("error", err))
+		}
+	}
+
+	debounce := time.NewTimer(reloadDebounce)
+	debounce.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-hup:
+			w.logger.Info("SIGHUP received")
+			w.Reload()
+		case event := <-fw.Events:
+			if abs, _ := filepath.Abs(event.Name); watched[abs] {
+				debounce.Reset(reloadDebounce)
+			}
+		case <-debounce.C:
+			w.Reload()
+		case err := <-fw.Errors:
+			w.logger.Warn("config watcher error", slog.Any("error", err))
+		}
+	}
+}
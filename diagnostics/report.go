@@ -0,0 +1,205 @@
+package diagnostics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/config"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/transcoding"
+)
+
+type Check struct {
+	Name  string
+	Value string
+	Errs  []error
+}
+
+type Report struct {
+	Checks []*Check
+}
+
+func (r *Report) Problems() int {
+	n := 0
+	for _, c := range r.Checks {
+		n += len(c.Errs)
+	}
+	return n
+}
+
+func (r *Report) Write(w io.Writer) {
+	fmt.Fprintln(w, "Configuration report:")
+	for _, c := range r.Checks {
+		status := "OK  "
+		if len(c.Errs) > 0 {
+			status = "FAIL"
+		}
+
+		line := fmt.Sprintf("  %s %s", status, c.Name)
+		if c.Value != "" {
+			line += fmt.Sprintf(" = %q", c.Value)
+		}
+		fmt.Fprintln(w, line)
+
+		for _, err := range c.Errs {
+			fmt.Fprintf(w, "         - %v\n", err)
+		}
+	}
+
+	if n := r.Problems(); n > 0 {
+		fmt.Fprintf(w, "%d problem(s) found\n", n)
+	} else {
+		fmt.Fprintln(w, "No problems found")
+	}
+}
+
+type Options struct {
+	Probe        bool
+	ProbeTimeout time.Duration
+}
+
+// Run checks every setting of conf. loadErr is an error returned by config loader
+// along with conf, which can be nil if config sources weren't read at all.
+func Run(conf *config.Config, loadErr error, opts Options) *Report {
+	report := &Report{}
+	byField := map[string]*Check{}
+
+	if conf != nil {
+		for _, setting := range conf.Settings() {
+			c := &Check{Name: setting[0], Value: setting[1]}
+			byField[c.Name] = c
+			report.Checks = append(report.Checks, c)
+		}
+	}
+
+	sources := &Check{Name: "config sources"}
+	addErrs := func(err error) {
+		for _, e := range flatten(err) {
+			var fe *config.FieldError
+			if errors.As(e, &fe) && byField[fe.Field] != nil {
+				byField[fe.Field].Errs = append(byField[fe.Field].Errs, errors.New(fe.Msg))
+			} else {
+				sources.Errs = append(sources.Errs, e)
+			}
+		}
+	}
+
+	addErrs(loadErr)
+	if conf == nil {
+		report.Checks = append(report.Checks, sources)
+		return report
+	}
+
+	addErrs(conf.Validate())
+	addErrs(checkTranscoding(conf))
+	if len(sources.Errs) > 0 {
+		report.Checks = append([]*Check{sources}, report.Checks...)
+	}
+
+	if opts.Probe {
+		report.Checks = append(report.Checks, probeBackends(conf, opts.ProbeTimeout)...)
+	}
+
+	return report
+}
+
+func flatten(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var errs []error
+		for _, e := range joined.Unwrap() {
+			errs = append(errs, flatten(e)...)
+		}
+		return errs
+	}
+	return []error{err}
+}
+
+func checkTranscoding(conf *config.Config) error {
+	var errs []error
+
+	if _, err := transcoding.ParseRules(conf.TranscodeRoutes); err != nil {
+		errs = append(errs, &config.FieldError{Field: "TRANSCODE_ROUTES", Msg: err.Error()})
+	}
+
+	for _, entry := range transcoding.ParseAllowlist(conf.RpcAllowlist) {
+		service, method, ok := strings.Cut(entry, "/")
+		if entry != "*" && (!ok || service == "" || method == "") {
+			errs = append(errs, &config.FieldError{
+				Field: "RPC_ALLOWLIST",
+				Msg:   fmt.Sprintf("entry %q must look like package.Service/Method or package.Service/*", entry),
+			})
+		}
+	}
+
+	sets, _ := conf.DescriptorSets()
+	for backend, path := range sets {
+		if _, err := transcoding.LoadDescriptorSet(path); err != nil {
+			errs = append(errs, &config.FieldError{
+				Field: "RPC_DESCRIPTOR_SETS",
+				Msg:   fmt.Sprintf("%s: %v", backend, err),
+			})
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func probeBackends(conf *config.Config, timeout time.Duration) []*Check {
+	var checks []*Check
+
+	backends := conf.Backends()
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		addr := backends[name]
+		// invalid addresses are already reported by validation
+		if addr == "" || config.ValidateAddr(addr) != nil {
+			continue
+		}
+
+		c := &Check{Name: "probe " + name, Value: addr}
+		checks = append(checks, c)
+		if err := probe(addr, timeout); err != nil {
+			c.Errs = append(c.Errs, err)
+		}
+	}
+
+	return checks
+}
+
+func probe(addr string, timeout time.Duration) error {
+	cc, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return err
+	}
+	defer cc.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cc.Connect()
+	for {
+		state := cc.GetState()
+		if state == connectivity.Ready {
+			return nil
+		}
+		if !cc.WaitForStateChange(ctx, state) {
+			return fmt.Errorf("not reachable within %s, last state %s", timeout, state)
+		}
+	}
+}
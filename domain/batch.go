@@ -0,0 +1,11 @@
+package domain
+
+// PredictionBatchItem is the result of the Item-th request of a batch,
+// counting from 1.
+type PredictionBatchItem struct {
+	Item       int                 `json:"item"`
+	Prediction *PredictionResponse `json:"prediction,omitempty"`
+	Error      string              `json:"error,omitempty"`
+}
+
+type PredictionBatchJob = Job[PredictionBatchItem]
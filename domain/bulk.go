@@ -0,0 +1,9 @@
+package domain
+
+type BulkRowResult struct {
+	Row       int    `json:"row"`
+	ListingId string `json:"listing_id,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+type BulkImportJob = Job[BulkRowResult]
@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+const (
+	ListingCreated = "created"
+	ListingUpdated = "updated"
+	ListingDeleted = "deleted"
+)
+
+type ListingEvent struct {
+	EventId   string      `json:"event_id"`
+	Type      string      `json:"type"`
+	ListingId string      `json:"listing_id"`
+	Listing   *CarListing `json:"listing,omitempty"`
+	At        time.Time   `json:"at"`
+}
+
+// StreamReset tells a stream client events since its Last-Event-ID are
+// lost, so it should reload listings.
+type StreamReset struct {
+	Reason string `json:"reason"`
+}
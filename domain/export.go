@@ -0,0 +1,11 @@
+package domain
+
+import "time"
+
+// ExportLinkResponse answers exports made with delivery=link.
+type ExportLinkResponse struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Rows      int       `json:"rows"`
+	Truncated bool      `json:"truncated,omitempty"`
+}
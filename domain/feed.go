@@ -35,6 +35,10 @@ type CarListing struct {
 	SellerRating     float64   `json:"seller_rating"`
 	SellerSalesCount int32     `json:"seller_sales_count"`
 	SellerIsBusiness bool      `json:"seller_is_business"`
+
+	// set by the gateway per request, feed service doesn't keep them
+	IsFavorite *bool             `json:"is_favorite,omitempty"`
+	Valuation  *ListingValuation `json:"valuation,omitempty"`
 }
 
 type PageRequest struct {
@@ -46,11 +50,39 @@ type PageResponseMetadata struct {
 	TotalItems  int32 `json:"total_items"`
 	TotalPages  int32 `json:"total_pages"`
 	CurrentPage int32 `json:"current_page"`
+	Filters     *ListingFilter `json:"filters,omitempty"`
+	Truncated   bool           `json:"truncated,omitempty"`
+	Sort        string         `json:"sort,omitempty"`
+	NextCursor  string         `json:"next_cursor,omitempty"`
+	PrevCursor  string         `json:"prev_cursor,omitempty"`
+
+	// listings with_valuation=true left without one
+	ValuationMissing int32 `json:"valuation_missing,omitempty"`
+}
+
+type ListingFilter struct {
+	Makes            []string `json:"make,omitempty"`
+	Models           []string `json:"model_name,omitempty"`
+	BodyTypes        []string `json:"body_type,omitempty"`
+	Transmissions    []string `json:"transmission,omitempty"`
+	Drivetrains      []string `json:"drivetrain,omitempty"`
+	EngineTypes      []string `json:"engine_type,omitempty"`
+	Colors           []string `json:"color,omitempty"`
+	Conditions       []string `json:"condition,omitempty"`
+	DealTypes        []string `json:"deal_type,omitempty"`
+	PriceMin         *float64 `json:"price_min,omitempty"`
+	PriceMax         *float64 `json:"price_max,omitempty"`
+	YearFrom         *int32   `json:"year_from,omitempty"`
+	YearTo           *int32   `json:"year_to,omitempty"`
+	MileageMin       *int32   `json:"mileage_min,omitempty"`
+	MileageMax       *int32   `json:"mileage_max,omitempty"`
+	SellerIsBusiness *bool    `json:"seller_is_business,omitempty"`
 }
 
 type ListListingsRequest struct {
-	Page   PageRequest `json:"page"`
-	SortBy string      `json:"sort_by"`
+	Page   PageRequest    `json:"page"`
+	SortBy string         `json:"sort_by"`
+	Filter *ListingFilter `json:"filter,omitempty"`
 }
 
 type ListListingsResponse struct {
@@ -59,14 +91,29 @@ type ListListingsResponse struct {
 }
 
 type SearchListingsRequest struct {
-	Query  string      `json:"query"`
-	Page   PageRequest `json:"page"`
-	SortBy string      `json:"sort_by"`
+	Query  string         `json:"query"`
+	Page   PageRequest    `json:"page"`
+	SortBy string         `json:"sort_by"`
+	Filter *ListingFilter `json:"filter,omitempty"`
 }
 
 type SearchListingsResponse struct {
 	Listings     []CarListing       `json:"listings"`
 	PageMetadata PageResponseMetadata `json:"page_metadata"`
+	Facets       map[string][]FacetBucket `json:"facets,omitempty"`
+}
+
+type FacetRequest struct {
+	Names       []string
+	PriceBucket float64
+	YearBucket  int32
+}
+
+type FacetBucket struct {
+	Value string   `json:"value"`
+	From  *float64 `json:"from,omitempty"`
+	To    *float64 `json:"to,omitempty"`
+	Count int      `json:"count"`
 }
 
 type GetListingResponse struct {
@@ -99,4 +146,13 @@ type AddToFavoritesRequest struct {
 
 type AddToFavoritesResponse struct {
 	Success bool `json:"success"`
-}
\ No newline at end of file
+}
+
+type ListFavoritesResponse struct {
+	Listings     []CarListing         `json:"listings"`
+	PageMetadata PageResponseMetadata `json:"page_metadata"`
+}
+
+type RemoveFromFavoritesResponse struct {
+	Success bool `json:"success"`
+}
@@ -0,0 +1,31 @@
+package domain
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// ListingFields are CarListing JSON field names in declaration order.
+var ListingFields = jsonFields(reflect.TypeFor[CarListing]())
+
+// StoredListingFields are ListingFields kept by feed service, without ones
+// the gateway sets per request.
+var StoredListingFields = slices.DeleteFunc(slices.Clone(ListingFields), func(name string) bool {
+	return name == "is_favorite" || name == "valuation"
+})
+
+func IsListingField(name string) bool {
+	return slices.Contains(ListingFields, name)
+}
+
+func jsonFields(t reflect.Type) []string {
+	names := make([]string, 0, t.NumField())
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+)
+
+func (f *ListingFilter) IsEmpty() bool {
+	return f == nil || (len(f.Makes) == 0 && len(f.Models) == 0 && len(f.BodyTypes) == 0 &&
+		len(f.Transmissions) == 0 && len(f.Drivetrains) == 0 && len(f.EngineTypes) == 0 &&
+		len(f.Colors) == 0 && len(f.Conditions) == 0 && len(f.DealTypes) == 0 &&
+		f.PriceMin == nil && f.PriceMax == nil && f.YearFrom == nil && f.YearTo == nil &&
+		f.MileageMin == nil && f.MileageMax == nil && f.SellerIsBusiness == nil)
+}
+
+// Validate checks that ranges of the filter aren't empty.
+func (f *ListingFilter) Validate() error {
+	if f == nil {
+		return nil
+	}
+
+	var errs []error
+	if f.PriceMin != nil && f.PriceMax != nil && *f.PriceMin > *f.PriceMax {
+		errs = append(errs, errors.New("price_min can't be greater than price_max"))
+	}
+	if f.YearFrom != nil && f.YearTo != nil && *f.YearFrom > *f.YearTo {
+		errs = append(errs, errors.New("year_from can't be greater than year_to"))
+	}
+	if f.MileageMin != nil && f.MileageMax != nil && *f.MileageMin > *f.MileageMax {
+		errs = append(errs, errors.New("mileage_min can't be greater than mileage_max"))
+	}
+	return errors.Join(errs...)
+}
+
+func (f *ListingFilter) Match(l *CarListing) bool {
+	if f == nil {
+		return true
+	}
+
+	return oneOf(f.Makes, l.Make) &&
+		oneOf(f.Models, l.ModelName) &&
+		oneOf(f.BodyTypes, l.BodyType) &&
+		oneOf(f.Transmissions, l.Transmission) &&
+		oneOf(f.Drivetrains, l.Drivetrain) &&
+		oneOf(f.EngineTypes, l.EngineType) &&
+		oneOf(f.Colors, l.Color) &&
+		oneOf(f.Conditions, l.Condition) &&
+		oneOf(f.DealTypes, l.DealType) &&
+		(f.PriceMin == nil || l.Price >= *f.PriceMin) &&
+		(f.PriceMax == nil || l.Price <= *f.PriceMax) &&
+		(f.YearFrom == nil || l.Year >= *f.YearFrom) &&
+		(f.YearTo == nil || l.Year <= *f.YearTo) &&
+		(f.MileageMin == nil || l.Mileage >= *f.MileageMin) &&
+		(f.MileageMax == nil || l.Mileage <= *f.MileageMax) &&
+		(f.SellerIsBusiness == nil || l.SellerIsBusiness == *f.SellerIsBusiness)
+}
+
+func oneOf(allowed []string, value string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, a := range allowed {
+		if strings.EqualFold(a, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+)
+
+const (
+	ForecastMaxYears      = 30
+	ForecastAnnualMileage = 15000
+)
+
+// ForecastRequest describes the car as it's now, the years to price it in
+// and how much it's expected to be driven a year.
+type ForecastRequest struct {
+	PredictionRequest
+	FromYear      int  `json:"from_year"`
+	ToYear        int  `json:"to_year"`
+	AnnualMileage *int `json:"annual_mileage"`
+}
+
+// SetDefaults fills FromYear with YearSell or thisYear and AnnualMileage
+// with ForecastAnnualMileage when they're not given.
+func (f *ForecastRequest) SetDefaults(thisYear int) {
+	if f.FromYear == 0 {
+		f.FromYear = cmp.Or(f.YearSell, thisYear)
+	}
+	if f.AnnualMileage == nil {
+		mileage := ForecastAnnualMileage
+		f.AnnualMileage = &mileage
+	}
+}
+
+func (f *ForecastRequest) Validate() error {
+	errs := []error{f.PredictionRequest.Validate()}
+
+	if f.FromYear < f.Year {
+		errs = append(errs, fmt.Errorf("from_year %d can't be before year %d", f.FromYear, f.Year))
+	}
+	if f.ToYear < f.FromYear || f.ToYear-f.FromYear >= ForecastMaxYears {
+		errs = append(errs, fmt.Errorf("to_year %d must be within %d years from from_year %d", f.ToYear, ForecastMaxYears, f.FromYear))
+	}
+	if f.AnnualMileage != nil && *f.AnnualMileage < 0 {
+		errs = append(errs, errors.New("annual_mileage can't be negative"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// ForecastPoint is the price predicted for the car sold in Year, having
+// Odometer then. Error is set instead when it couldn't be predicted.
+type ForecastPoint struct {
+	Year     int    `json:"year"`
+	Odometer int    `json:"odometer"`
+	Price    int    `json:"price,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+type ForecastResponse struct {
+	Points []ForecastPoint `json:"points"`
+}
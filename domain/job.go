@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+const (
+	JobPending = "pending"
+	JobRunning = "running"
+	JobDone    = "done"
+)
+
+// Job is a background run over Total items, Results are in the order of the
+// items.
+type Job[R any] struct {
+	JobId      string     `json:"job_id"`
+	Status     string     `json:"status"`
+	Total      int        `json:"total"`
+	Processed  int        `json:"processed"`
+	Succeeded  int        `json:"succeeded"`
+	Failed     int        `json:"failed"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Results    []R        `json:"results,omitempty"`
+}
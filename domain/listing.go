@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Validate checks listing data a client sends for creation.
+func (l *CarListing) Validate() error {
+	var errs []error
+
+	if l.ListingId != "" {
+		errs = append(errs, errors.New("listing_id is assigned by the service"))
+	}
+	if l.Make == "" {
+		errs = append(errs, errors.New("make is required"))
+	}
+	if l.ModelName == "" {
+		errs = append(errs, errors.New("model_name is required"))
+	}
+	if maxYear := int32(time.Now().Year() + 1); l.Year < 1886 || l.Year > maxYear {
+		errs = append(errs, fmt.Errorf("year %d must be between 1886 and %d", l.Year, maxYear))
+	}
+	if l.Price < 0 {
+		errs = append(errs, fmt.Errorf("price %v can't be negative", l.Price))
+	}
+	if l.Mileage < 0 || l.OwnersCount < 0 || l.AccidentsCount < 0 || l.EnginePower < 0 {
+		errs = append(errs, errors.New("mileage, owners_count, accidents_count and engine_power can't be negative"))
+	}
+
+	return errors.Join(errs...)
+}
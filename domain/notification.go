@@ -0,0 +1,38 @@
+package domain
+
+import "time"
+
+const (
+	NotifySavedSearch    = "saved_search"
+	NotifyPriceChange    = "price_change"
+	NotifyStatusChange   = "status_change"
+	NotifyListingRemoved = "listing_removed"
+)
+
+// ListingChange is what a watched listing changed from and to.
+type ListingChange struct {
+	OldPrice  float64 `json:"old_price"`
+	NewPrice  float64 `json:"new_price"`
+	OldStatus string  `json:"old_status"`
+	NewStatus string  `json:"new_status"`
+}
+
+type Notification struct {
+	NotificationId string         `json:"notification_id"`
+	UserId         string         `json:"user_id"`
+	Kind           string         `json:"kind"`
+	SubjectId      string         `json:"subject_id"`
+	Title          string         `json:"title"`
+	Listings       []CarListing   `json:"listings,omitempty"`
+	Change         *ListingChange `json:"change,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+}
+
+type ListNotificationsResponse struct {
+	Notifications []Notification       `json:"notifications"`
+	PageMetadata  PageResponseMetadata `json:"page_metadata"`
+}
+
+type DeleteNotificationResponse struct {
+	Success bool `json:"success"`
+}
@@ -1,5 +1,11 @@
 package domain
 
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
 type PredictionRequest struct {
 	Make     string `json:"make"`
 	Model    string `json:"model"`
@@ -11,13 +17,76 @@ type PredictionRequest struct {
 	Color    string `json:"color"`
 }
 
+// Validate checks request is complete enough for the prediction service.
+func (p *PredictionRequest) Validate() error {
+	var errs []error
+
+	if p.Make == "" {
+		errs = append(errs, errors.New("make is required"))
+	}
+	if p.Model == "" {
+		errs = append(errs, errors.New("model is required"))
+	}
+	if maxYear := time.Now().Year() + 1; p.Year < 1886 || p.Year > maxYear {
+		errs = append(errs, fmt.Errorf("year %d must be between 1886 and %d", p.Year, maxYear))
+	}
+	if p.YearSell != 0 && p.YearSell < p.Year {
+		errs = append(errs, fmt.Errorf("yearSell %d can't be before year %d", p.YearSell, p.Year))
+	}
+	if p.Hp < 0 || p.Odometer < 0 {
+		errs = append(errs, errors.New("hp and odometer can't be negative"))
+	}
+
+	return errors.Join(errs...)
+}
+
 type PredictionResponse struct {
 	Price     int      `json:"price"`
 	SellCount int      `json:"sell_count"`
 	Urls      []string `json:"urls"`
-	GraphImg  string   `json:"graph_img"`
+	GraphImg  string   `json:"graph_img,omitempty"`
+	GraphURL  string   `json:"graph_url,omitempty"`
+
+	Explanation *PredictionExplanation `json:"explanation,omitempty"`
+}
+
+const ExplanationSensitivity = "sensitivity"
+
+// FeatureContribution is how much the price changes when Feature grows by
+// Step.
+type FeatureContribution struct {
+	Feature     string `json:"feature"`
+	Step        int    `json:"step"`
+	PriceChange int    `json:"price_change"`
+}
+
+// PredictionExplanation is sent with explain=true. Source tells how it was
+// made, sensitivity ones come from re-predicting the car with features
+// changed by a step: the range spans those prices, and confidence falls as
+// the range widens relative to the price.
+type PredictionExplanation struct {
+	Source        string                `json:"source"`
+	PriceLow      int                   `json:"price_low"`
+	PriceHigh     int                   `json:"price_high"`
+	Confidence    float64               `json:"confidence"`
+	Contributions []FeatureContribution `json:"contributions"`
 }
 
 type ImageResponse struct {
 	Urls []string `json:"urls"`
 }
+
+const (
+	SessionPrediction  = "prediction"
+	SessionError       = "error"
+	SessionRateLimited = "rate_limited"
+)
+
+// PredictionSessionMessage is sent over /prediction/ws, Seq is the number of
+// the request message it answers, counting from 1.
+type PredictionSessionMessage struct {
+	Type       string              `json:"type"`
+	Seq        int                 `json:"seq"`
+	Prediction *PredictionResponse `json:"prediction,omitempty"`
+	Error      string              `json:"error,omitempty"`
+}
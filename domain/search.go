@@ -0,0 +1,33 @@
+package domain
+
+import "time"
+
+type SavedSearch struct {
+	SearchId  string         `json:"search_id"`
+	UserId    string         `json:"user_id"`
+	Name      string         `json:"name"`
+	Query     string         `json:"query"`
+	Filter    *ListingFilter `json:"filter,omitempty"`
+	Sort      string         `json:"sort,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+	LastRunAt *time.Time     `json:"last_run_at,omitempty"`
+}
+
+type SaveSearchRequest struct {
+	Name   string         `json:"name"`
+	Query  string         `json:"query"`
+	Filter *ListingFilter `json:"filter,omitempty"`
+	Sort   string         `json:"sort,omitempty"`
+}
+
+type ListSavedSearchesResponse struct {
+	Searches []SavedSearch `json:"searches"`
+}
+
+type DeleteSavedSearchResponse struct {
+	Success bool `json:"success"`
+}
+
+type SavedSearchResponse struct {
+	Search SavedSearch `json:"search"`
+}
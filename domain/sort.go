@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// SortKey is one field of sort=price,-year spec, "-" prefix meaning descending.
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+type Sort []SortKey
+
+// sortFields are CarListing fields listings can be sorted by, named as in JSON.
+var sortFields = map[string]func(a, b *CarListing) int{
+	"posted_at":          func(a, b *CarListing) int { return a.PostedAt.Compare(b.PostedAt) },
+	"price":              func(a, b *CarListing) int { return cmp.Compare(a.Price, b.Price) },
+	"mileage":            func(a, b *CarListing) int { return cmp.Compare(a.Mileage, b.Mileage) },
+	"year":               func(a, b *CarListing) int { return cmp.Compare(a.Year, b.Year) },
+	"engine_power":       func(a, b *CarListing) int { return cmp.Compare(a.EnginePower, b.EnginePower) },
+	"owners_count":       func(a, b *CarListing) int { return cmp.Compare(a.OwnersCount, b.OwnersCount) },
+	"accidents_count":    func(a, b *CarListing) int { return cmp.Compare(a.AccidentsCount, b.AccidentsCount) },
+	"weight_kg":          func(a, b *CarListing) int { return cmp.Compare(a.WeightKg, b.WeightKg) },
+	"seller_rating":      func(a, b *CarListing) int { return cmp.Compare(a.SellerRating, b.SellerRating) },
+	"seller_sales_count": func(a, b *CarListing) int { return cmp.Compare(a.SellerSalesCount, b.SellerSalesCount) },
+	"make":               func(a, b *CarListing) int { return compareFold(a.Make, b.Make) },
+	"model_name":         func(a, b *CarListing) int { return compareFold(a.ModelName, b.ModelName) },
+	"body_type":          func(a, b *CarListing) int { return compareFold(a.BodyType, b.BodyType) },
+	"color":              func(a, b *CarListing) int { return compareFold(a.Color, b.Color) },
+	"condition":          func(a, b *CarListing) int { return compareFold(a.Condition, b.Condition) },
+}
+
+func IsSortField(name string) bool {
+	_, ok := sortFields[name]
+	return ok
+}
+
+func SortFields() []string {
+	return slices.Sorted(maps.Keys(sortFields))
+}
+
+func compareFold(a, b string) int {
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+// Compare orders listings by sort keys in turn, 0 meaning they are equal in all of them.
+func (s Sort) Compare(a, b *CarListing) int {
+	for _, k := range s {
+		c := sortFields[k.Field](a, b)
+		if k.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func (s Sort) String() string {
+	keys := make([]string, len(s))
+	for i, k := range s {
+		keys[i] = k.Field
+		if k.Desc {
+			keys[i] = "-" + k.Field
+		}
+	}
+	return strings.Join(keys, ",")
+}
@@ -0,0 +1,51 @@
+package domain
+
+import "math"
+
+const (
+	RatingGoodDeal   = "good_deal"
+	RatingFair       = "fair"
+	RatingOverpriced = "overpriced"
+)
+
+// ValuationThresholds are how far, in percent of the predicted price, asking
+// price has to be below it for a good deal or above it to be overpriced.
+type ValuationThresholds struct {
+	GoodDealPct   float64
+	OverpricedPct float64
+}
+
+// ListingValuation is the part of Valuation listings are enriched with.
+type ListingValuation struct {
+	PredictedPrice float64 `json:"predicted_price"`
+	Delta          float64 `json:"delta"`
+	DeltaPercent   float64 `json:"delta_percent"`
+	Rating         string  `json:"rating"`
+}
+
+type Valuation struct {
+	ListingId   string  `json:"listing_id"`
+	AskingPrice float64 `json:"asking_price"`
+	ListingValuation
+	Inputs PredictionRequest `json:"inputs"`
+}
+
+// Rate fills delta and rating of the valuation from its prices, predicted
+// price has to be positive.
+func (t ValuationThresholds) Rate(v *Valuation) {
+	v.Delta = v.AskingPrice - v.PredictedPrice
+	v.DeltaPercent = math.Round(v.Delta/v.PredictedPrice*10000) / 100
+
+	switch {
+	case v.DeltaPercent <= -t.GoodDealPct:
+		v.Rating = RatingGoodDeal
+	case v.DeltaPercent >= t.OverpricedPct:
+		v.Rating = RatingOverpriced
+	default:
+		v.Rating = RatingFair
+	}
+}
+
+type ValuationResponse struct {
+	Valuation Valuation `json:"valuation"`
+}
@@ -0,0 +1,26 @@
+package domain
+
+import "time"
+
+// ListingSnapshot is the state of a watched listing changes are detected against.
+type ListingSnapshot struct {
+	ListingId string    `json:"listing_id"`
+	Price     float64   `json:"price"`
+	Status    string    `json:"status"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+type WatchedListing struct {
+	ListingSnapshot
+	UserIds []string `json:"user_ids"`
+}
+
+type WatchListingResponse struct {
+	ListingId string          `json:"listing_id"`
+	UserId    string          `json:"user_id"`
+	Snapshot  ListingSnapshot `json:"snapshot"`
+}
+
+type UnwatchListingResponse struct {
+	Success bool `json:"success"`
+}
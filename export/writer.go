@@ -0,0 +1,111 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Writer writes rows of values in columns order given on creation.
+type Writer interface {
+	Write(values []any) error
+	Close() error
+}
+
+type Format struct {
+	ContentType string
+	Extension   string
+}
+
+var Formats = map[string]Format{
+	"csv":    {"text/csv; charset=utf-8", "csv"},
+	"ndjson": {"application/x-ndjson", "ndjson"},
+	"xlsx":   {"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", "xlsx"},
+}
+
+// NewWriter starts output of format to w, writing column names header where
+// format has one.
+func NewWriter(format string, w io.Writer, columns []string) (Writer, error) {
+	switch format {
+	case "csv":
+		cw := &csvWriter{w: csv.NewWriter(w)}
+		return cw, cw.w.Write(columns)
+	case "ndjson":
+		return &ndjsonWriter{w: w, columns: columns}, nil
+	case "xlsx":
+		return newXLSXWriter(w, columns)
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+// formatText renders value for text cells, tags are joined with "|" as the
+// bulk import expects them.
+func formatText(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case []string:
+		return strings.Join(v, "|")
+	}
+	return fmt.Sprint(v)
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvWriter) Write(values []any) error {
+	record := make([]string, len(values))
+	for i, v := range values {
+		record[i] = formatText(v)
+	}
+	return c.w.Write(record)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type ndjsonWriter struct {
+	w       io.Writer
+	columns []string
+	buf     bytes.Buffer
+}
+
+func (n *ndjsonWriter) Write(values []any) error {
+	n.buf.Reset()
+	n.buf.WriteByte('{')
+	for i, v := range values {
+		if i > 0 {
+			n.buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(n.columns[i])
+		value, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		n.buf.Write(name)
+		n.buf.WriteByte(':')
+		n.buf.Write(value)
+	}
+	n.buf.WriteString("}\n")
+
+	_, err := n.w.Write(n.buf.Bytes())
+	return err
+}
+
+func (n *ndjsonWriter) Close() error {
+	return nil
+}
@@ -0,0 +1,106 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	xmlHeader  = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n"
+	sheetNS    = "http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+	relsNS     = "http://schemas.openxmlformats.org/package/2006/relationships"
+	officeRels = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+)
+
+// staticParts are the package files of a single sheet workbook besides the sheet itself.
+var staticParts = [][2]string{
+	{"[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", `<Relationships xmlns="` + relsNS + `">` +
+		`<Relationship Id="rId1" Type="` + officeRels + `/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/workbook.xml", `<workbook xmlns="` + sheetNS + `" xmlns:r="` + officeRels + `">` +
+		`<sheets><sheet name="Listings" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<Relationships xmlns="` + relsNS + `">` +
+		`<Relationship Id="rId1" Type="` + officeRels + `/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+// xlsxWriter streams a minimal workbook: zip entries are written with data
+// descriptors, so rows go to the client as they come, with inline strings
+// instead of a shared strings table that would need all rows upfront.
+type xlsxWriter struct {
+	zw    *zip.Writer
+	sheet io.Writer
+	row   strings.Builder
+}
+
+func newXLSXWriter(w io.Writer, columns []string) (*xlsxWriter, error) {
+	x := &xlsxWriter{zw: zip.NewWriter(w)}
+
+	for _, part := range staticParts {
+		f, err := x.zw.Create(part[0])
+		if err != nil {
+			return nil, err
+		}
+		if _, err = io.WriteString(f, xmlHeader+part[1]); err != nil {
+			return nil, err
+		}
+	}
+
+	sheet, err := x.zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	x.sheet = sheet
+	if _, err = io.WriteString(sheet, xmlHeader+`<worksheet xmlns="`+sheetNS+`"><sheetData>`); err != nil {
+		return nil, err
+	}
+
+	header := make([]any, len(columns))
+	for i, c := range columns {
+		header[i] = c
+	}
+	return x, x.Write(header)
+}
+
+func (x *xlsxWriter) Write(values []any) error {
+	x.row.Reset()
+	x.row.WriteString("<row>")
+	for _, v := range values {
+		switch v := v.(type) {
+		case int32, float64:
+			x.row.WriteString("<c><v>" + formatText(v) + "</v></c>")
+		case bool:
+			b := "0"
+			if v {
+				b = "1"
+			}
+			x.row.WriteString(`<c t="b"><v>` + b + "</v></c>")
+		case time.Time:
+			x.row.WriteString(`<c t="inlineStr"><is><t>` + v.Format(time.RFC3339) + "</t></is></c>")
+		default:
+			x.row.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+			xml.EscapeText(&x.row, []byte(formatText(v)))
+			x.row.WriteString("</t></is></c>")
+		}
+	}
+	x.row.WriteString("</row>")
+
+	_, err := io.WriteString(x.sheet, x.row.String())
+	return err
+}
+
+func (x *xlsxWriter) Close() error {
+	if _, err := io.WriteString(x.sheet, "</sheetData></worksheet>"); err != nil {
+		return err
+	}
+	return x.zw.Close()
+}
@@ -0,0 +1,91 @@
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+
+	_ "image/gif"
+	_ "image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	jpegQuality = 80
+	// maxPixels keeps small files of huge images from taking all memory
+	maxPixels = 50_000_000
+)
+
+// Preset is a size photos are served in. Cropped presets cover the whole
+// box, the others fit into it. Images are never scaled up.
+type Preset struct {
+	Width  int
+	Height int
+	Crop   bool
+}
+
+var Presets = map[string]Preset{
+	"thumb": {Width: 160, Height: 120, Crop: true},
+	"card":  {Width: 480, Height: 360, Crop: true},
+	"full":  {Width: 1600, Height: 1200},
+}
+
+// Decode reads JPEG, PNG, GIF or WebP image of at most maxPixels pixels.
+func Decode(r io.Reader) (image.Image, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width*config.Height > maxPixels {
+		return nil, fmt.Errorf("image of %dx%d is too large", config.Width, config.Height)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+// Resize scales img to preset, cropping the center when preset crops.
+func Resize(img image.Image, p Preset) image.Image {
+	src := img.Bounds()
+	sw, sh := src.Dx(), src.Dy()
+	if sw == 0 || sh == 0 {
+		return img
+	}
+
+	if p.Crop {
+		// the largest part of img with preset aspect ratio
+		cw, ch := sw, sw*p.Height/p.Width
+		if ch > sh {
+			cw, ch = sh*p.Width/p.Height, sh
+		}
+		x, y := src.Min.X+(sw-cw)/2, src.Min.Y+(sh-ch)/2
+		src = image.Rect(x, y, x+cw, y+ch)
+		sw, sh = cw, ch
+	}
+
+	// scale is min(p.Width/sw, p.Height/sh, 1)
+	w, h := sw, sh
+	if w > p.Width {
+		w, h = p.Width, sh*p.Width/sw
+	}
+	if h > p.Height {
+		w, h = sw*p.Height/sh, p.Height
+	}
+	w, h = max(w, 1), max(h, 1)
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, src, draw.Src, nil)
+	return dst
+}
+
+func EncodeJPEG(w io.Writer, img image.Image) error {
+	return jpeg.Encode(w, img, &jpeg.Options{Quality: jpegQuality})
+}
@@ -1,12 +1,13 @@
 package logger
 
 import (
+	"fmt"
 	"io"
 	"log/slog"
 	"os"
 )
 
-func SetupLogger(env, filename string) (*slog.Logger, error) {
+func SetupLogger(env, filename string, level slog.Leveler) (*slog.Logger, error) {
 	var log *slog.Logger
 	var out io.Writer
 
@@ -23,12 +24,14 @@ func SetupLogger(env, filename string) (*slog.Logger, error) {
     switch env {
     case "local":
         log = slog.New(
-			slog.NewTextHandler(out, &slog.HandlerOptions{AddSource: true, Level: slog.LevelDebug}),
+			slog.NewTextHandler(out, &slog.HandlerOptions{AddSource: true, Level: level}),
 		)
     case "production":
         log = slog.New(
-			slog.NewJSONHandler(out, &slog.HandlerOptions{Level: slog.LevelInfo}),
+			slog.NewJSONHandler(out, &slog.HandlerOptions{Level: level}),
 		)
+    default:
+        return nil, fmt.Errorf("unknown mode %q, expected local or production", env)
     }
 
     return log, nil
@@ -1,27 +1,83 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log"
+	"log/slog"
+	"os"
+	"time"
 
 	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/config"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/diagnostics"
 	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/logger"
 	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/server"
 )
 
 func main() {
-	conf, err := config.Load(".env")
+	if len(os.Args) > 1 && os.Args[1] == "validate-config" {
+		os.Exit(validateConfig(os.Args[2:]))
+	}
+
+	loader, err := config.NewLoader(flag.NewFlagSet("gateway", flag.ContinueOnError), os.Args[1:])
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	conf, err := loader.Load()
+	if err != nil || conf.Validate() != nil {
+		diagnostics.Run(conf, err, diagnostics.Options{}).Write(os.Stderr)
+		os.Exit(1)
+	}
+
+	level := new(slog.LevelVar)
+	lvl, _ := conf.Level()
+	level.Set(lvl)
+
+	logger, err := logger.SetupLogger(conf.Env, "", level)
 	if err != nil {
 		log.Fatalln(err)
 	}
 
-	logger, err := logger.SetupLogger(conf.Env, "")
+	s, err := server.NewServer(conf, logger)
 	if err != nil {
 		log.Fatalln(err)
 	}
 
-	s := server.NewServer(conf, logger)
+	watcher := config.NewWatcher(loader, conf, logger)
+	watcher.OnReload(func(c *config.Config) {
+		lvl, _ := c.Level()
+		level.Set(lvl)
+		s.ApplyConfig(c)
+	})
+	go func() {
+		if err := watcher.Run(context.Background()); err != nil {
+			logger.Error("config watcher stopped", slog.Any("error", err))
+		}
+	}()
 
 	if err = s.Run(); err != nil {
 		log.Fatalln(err)
 	}
-}
\ No newline at end of file
+}
+
+// validateConfig prints configuration report and returns process exit code.
+func validateConfig(args []string) int {
+	fset := flag.NewFlagSet("validate-config", flag.ContinueOnError)
+	probe := fset.Bool("probe", false, "check that backends accept connections")
+	timeout := fset.Duration("probe-timeout", 3*time.Second, "connectivity probe timeout per backend")
+
+	loader, err := config.NewLoader(fset, args)
+	if err != nil {
+		return 2
+	}
+
+	conf, err := loader.Load()
+	report := diagnostics.Run(conf, err, diagnostics.Options{Probe: *probe, ProbeTimeout: *timeout})
+	report.Write(os.Stdout)
+
+	if report.Problems() > 0 {
+		return 1
+	}
+	return 0
+}
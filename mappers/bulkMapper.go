@@ -0,0 +1,102 @@
+package mappers
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/domain"
+)
+
+// listingColumns maps CarListing JSON names to struct field indexes.
+var listingColumns = func() map[string]int {
+	t := reflect.TypeFor[domain.CarListing]()
+	columns := map[string]int{}
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		columns[name] = i
+	}
+	return columns
+}()
+
+// ToCSVColumns checks CSV header, which names CarListing fields.
+func ToCSVColumns(header []string) ([]int, error) {
+	columns := make([]int, len(header))
+	seen := map[string]bool{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		idx, ok := listingColumns[name]
+		if !ok || !slices.Contains(domain.StoredListingFields, name) {
+			return nil, fmt.Errorf("unknown column %q, expected listing fields %s", name, strings.Join(domain.StoredListingFields, ", "))
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("column %q is repeated", name)
+		}
+		seen[name] = true
+		columns[i] = idx
+	}
+	return columns, nil
+}
+
+// ToListingFromCSV fills listing from CSV record. Empty cells leave zero
+// values, tags are separated by "|".
+func ToListingFromCSV(columns []int, record []string) (*domain.CarListing, error) {
+	l := &domain.CarListing{}
+	v := reflect.ValueOf(l).Elem()
+
+	for i, raw := range record {
+		if raw = strings.TrimSpace(raw); raw == "" {
+			continue
+		}
+		field := v.Field(columns[i])
+
+		var err error
+		switch field.Interface().(type) {
+		case string:
+			field.SetString(raw)
+		case int32:
+			var n int64
+			n, err = strconv.ParseInt(raw, 10, 32)
+			field.SetInt(n)
+		case float64:
+			var f float64
+			f, err = strconv.ParseFloat(raw, 64)
+			field.SetFloat(f)
+		case bool:
+			var b bool
+			b, err = strconv.ParseBool(raw)
+			field.SetBool(b)
+		case []string:
+			field.Set(reflect.ValueOf(strings.Split(raw, "|")))
+		case time.Time:
+			var t time.Time
+			t, err = time.Parse(time.RFC3339, raw)
+			field.Set(reflect.ValueOf(t))
+		}
+		if err != nil {
+			name, _, _ := strings.Cut(v.Type().Field(columns[i]).Tag.Get("json"), ",")
+			return nil, fmt.Errorf("%s has wrong format: %q", name, raw)
+		}
+	}
+	return l, nil
+}
+
+// ToExportRow picks values of columns, which are CarListing JSON names, from listing.
+func ToExportRow(l *domain.CarListing, columns []string) []any {
+	v := reflect.ValueOf(l).Elem()
+	row := make([]any, len(columns))
+	for i, name := range columns {
+		field := v.Field(listingColumns[name])
+		if field.Kind() == reflect.Pointer {
+			if field.IsNil() {
+				continue
+			}
+			field = field.Elem()
+		}
+		row[i] = field.Interface()
+	}
+	return row
+}
@@ -0,0 +1,52 @@
+package mappers
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/domain"
+)
+
+var FacetNames = []string{
+	"make", "model_name", "body_type", "transmission", "drivetrain",
+	"engine_type", "color", "condition", "deal_type", "price", "year",
+}
+
+// ToFacetRequest reads facets=make,price&price_bucket=5000&year_bucket=5 params.
+func ToFacetRequest(q url.Values) (*domain.FacetRequest, error) {
+	names := multi(q, "facets")
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	var errs []error
+	req := &domain.FacetRequest{PriceBucket: 5000, YearBucket: 5}
+	for _, name := range names {
+		name = strings.ToLower(name)
+		if !slices.Contains(FacetNames, name) {
+			errs = append(errs, fmt.Errorf("unknown facet %q, expected one of %s", name, strings.Join(FacetNames, ", ")))
+			continue
+		}
+		if !slices.Contains(req.Names, name) {
+			req.Names = append(req.Names, name)
+		}
+	}
+
+	if v := parseParam(q, "price_bucket", parseFloat, &errs); v != nil {
+		req.PriceBucket = *v
+	}
+	if v := parseParam(q, "year_bucket", parseInt32, &errs); v != nil {
+		req.YearBucket = *v
+	}
+	if req.PriceBucket <= 0 || req.YearBucket <= 0 {
+		errs = append(errs, errors.New("price_bucket and year_bucket must be positive"))
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return req, nil
+}
@@ -14,6 +14,7 @@ func ToDomain(message *feed.CarListing) *domain.CarListing {
 		Status:           message.Status,
 		DealType:         message.DealType,
 		Price:            message.Price,
+		Tags:             message.Tags,
 		CarId:            message.CarId,
 		Mileage:          message.Mileage,
 		OwnersCount:      message.OwnersCount,
@@ -0,0 +1,52 @@
+package mappers
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/domain"
+)
+
+// ToFieldSet reads fields=listing_id,price,card param, where items are listing
+// fields or names of presets. Fields are returned in CarListing order, nil
+// meaning the whole listing.
+func ToFieldSet(q url.Values, presets map[string][]string) ([]string, error) {
+	names := multi(q, "fields")
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	var errs []error
+	selected := map[string]bool{}
+	for _, name := range names {
+		name = strings.ToLower(name)
+		if preset, ok := presets[name]; ok {
+			for _, f := range preset {
+				selected[f] = true
+			}
+			continue
+		}
+		if !domain.IsListingField(name) {
+			errs = append(errs, fmt.Errorf("unknown field %q, expected listing fields or one of presets %s",
+				name, strings.Join(slices.Sorted(maps.Keys(presets)), ", ")))
+			continue
+		}
+		selected[name] = true
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	var fields []string
+	for _, f := range domain.ListingFields {
+		if selected[f] {
+			fields = append(fields, f)
+		}
+	}
+	return fields, nil
+}
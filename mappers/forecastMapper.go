@@ -0,0 +1,47 @@
+package mappers
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/domain"
+)
+
+// ToForecastRequest reads forecast query params, named as ForecastRequest
+// JSON fields. Missing numbers are left zero, or nil.
+func ToForecastRequest(q url.Values) (*domain.ForecastRequest, error) {
+	f := &domain.ForecastRequest{
+		PredictionRequest: domain.PredictionRequest{
+			Make:  q.Get("make"),
+			Model: q.Get("model"),
+			Body:  q.Get("body"),
+			Color: q.Get("color"),
+		},
+	}
+
+	var errs []error
+	ints := []struct {
+		key string
+		dst *int
+	}{
+		{"year", &f.Year},
+		{"hp", &f.Hp},
+		{"yearSell", &f.YearSell},
+		{"odometer", &f.Odometer},
+		{"from_year", &f.FromYear},
+		{"to_year", &f.ToYear},
+	}
+	for _, param := range ints {
+		if v := parseParam(q, param.key, strconv.Atoi, &errs); v != nil {
+			*param.dst = *v
+		}
+	}
+
+	f.AnnualMileage = parseParam(q, "annual_mileage", strconv.Atoi, &errs)
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return f, nil
+}
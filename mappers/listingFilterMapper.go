@@ -0,0 +1,90 @@
+package mappers
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/domain"
+)
+
+// ToListingFilter reads filter query params. Multi-value params can be
+// repeated (make=bmw&make=audi) or comma separated (make=bmw,audi).
+func ToListingFilter(q url.Values) (*domain.ListingFilter, error) {
+	f := &domain.ListingFilter{
+		Makes:         multi(q, "make"),
+		Models:        multi(q, "model_name"),
+		BodyTypes:     multi(q, "body_type"),
+		Transmissions: multi(q, "transmission"),
+		Drivetrains:   multi(q, "drivetrain"),
+		EngineTypes:   multi(q, "engine_type"),
+		Colors:        multi(q, "color"),
+		Conditions:    multi(q, "condition"),
+		DealTypes:     multi(q, "deal_type"),
+	}
+
+	var errs []error
+	f.PriceMin = parseParam(q, "price_min", parseFloat, &errs)
+	f.PriceMax = parseParam(q, "price_max", parseFloat, &errs)
+	f.YearFrom = parseParam(q, "year_from", parseInt32, &errs)
+	f.YearTo = parseParam(q, "year_to", parseInt32, &errs)
+	f.MileageMin = parseParam(q, "mileage_min", parseInt32, &errs)
+	f.MileageMax = parseParam(q, "mileage_max", parseInt32, &errs)
+	f.SellerIsBusiness = parseParam(q, "seller_is_business", strconv.ParseBool, &errs)
+
+	if err := f.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	if f.IsEmpty() {
+		return nil, nil
+	}
+	return f, nil
+}
+
+func multi(q url.Values, key string) []string {
+	var values []string
+	for _, v := range q[key] {
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				values = append(values, part)
+			}
+		}
+	}
+	return values
+}
+
+func parseParam[T any](q url.Values, key string, parse func(string) (T, error), errs *[]error) *T {
+	raw := q.Get(key)
+	if raw == "" {
+		return nil
+	}
+
+	v, err := parse(raw)
+	if err != nil {
+		*errs = append(*errs, fmt.Errorf("%s has wrong format: %q", key, raw))
+		return nil
+	}
+	return &v
+}
+
+func parseFloat(s string) (float64, error) {
+	v, err := strconv.ParseFloat(s, 64)
+	if err == nil && v < 0 {
+		err = errors.New("negative value")
+	}
+	return v, err
+}
+
+func parseInt32(s string) (int32, error) {
+	v, err := strconv.ParseInt(s, 10, 32)
+	if err == nil && v < 0 {
+		err = errors.New("negative value")
+	}
+	return int32(v), err
+}
@@ -0,0 +1,113 @@
+package mappers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	model "github.com/nikita-itmo-gh-acc/car_estimator_api_contracts/gen/prediction_v1/go"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/domain"
+)
+
+func ToPredictRequest(params *domain.PredictionRequest) *model.PredictRequest {
+	return &model.PredictRequest{
+		Make:     params.Make,
+		Model:    params.Model,
+		Year:     int32(params.Year),
+		Hp:       int32(params.Hp),
+		Body:     params.Body,
+		Yearsell: int32(params.YearSell),
+		Odometer: int32(params.Odometer),
+		Color:    params.Color,
+	}
+}
+
+// ToPredictionResponse links the graph by graphURL, or inlines it as base64
+// when graphURL is empty.
+func ToPredictionResponse(response *model.PredictResponse, graphURL string) *domain.PredictionResponse {
+	prediction := &domain.PredictionResponse{
+		Price:     int(response.GetPrice()),
+		SellCount: int(response.GetSellCount()),
+		Urls:      response.GetPhotoUrls(),
+		GraphURL:  graphURL,
+	}
+	if graphURL == "" {
+		prediction.GraphImg = base64.StdEncoding.EncodeToString(response.GetGraphPng())
+	}
+	return prediction
+}
+
+// ToPredictionRequest describes the car of listing for the prediction
+// service, as if it's sold in yearSell.
+func ToPredictionRequest(l *domain.CarListing, yearSell int) *domain.PredictionRequest {
+	return &domain.PredictionRequest{
+		Make:     l.Make,
+		Model:    l.ModelName,
+		Year:     int(l.Year),
+		Hp:       int(l.EnginePower),
+		Body:     l.BodyType,
+		YearSell: yearSell,
+		Odometer: int(l.Mileage),
+		Color:    l.Color,
+	}
+}
+
+// predictionColumns maps lowercased PredictionRequest JSON names to struct
+// field indexes.
+var predictionColumns = func() map[string]int {
+	t := reflect.TypeFor[domain.PredictionRequest]()
+	columns := map[string]int{}
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		columns[strings.ToLower(name)] = i
+	}
+	return columns
+}()
+
+// ToPredictionCSVColumns checks CSV header, which names PredictionRequest
+// fields, case-insensitively.
+func ToPredictionCSVColumns(header []string) ([]int, error) {
+	columns := make([]int, len(header))
+	seen := map[int]bool{}
+	for i, name := range header {
+		idx, ok := predictionColumns[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q, expected make, model, year, hp, body, yearSell, odometer, color", name)
+		}
+		if seen[idx] {
+			return nil, fmt.Errorf("column %q is repeated", name)
+		}
+		seen[idx] = true
+		columns[i] = idx
+	}
+	return columns, nil
+}
+
+// ToPredictionRequestFromCSV fills request from CSV record, empty cells leave
+// zero values.
+func ToPredictionRequestFromCSV(columns []int, record []string) (*domain.PredictionRequest, error) {
+	p := &domain.PredictionRequest{}
+	v := reflect.ValueOf(p).Elem()
+
+	for i, raw := range record {
+		if raw = strings.TrimSpace(raw); raw == "" {
+			continue
+		}
+		field := v.Field(columns[i])
+
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(raw)
+		case reflect.Int:
+			n, err := strconv.Atoi(raw)
+			if err != nil {
+				name, _, _ := strings.Cut(v.Type().Field(columns[i]).Tag.Get("json"), ",")
+				return nil, fmt.Errorf("%s has wrong format: %q", name, raw)
+			}
+			field.SetInt(int64(n))
+		}
+	}
+	return p, nil
+}
@@ -0,0 +1,75 @@
+package mappers
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	feed "github.com/nikita-itmo-gh-acc/car_estimator_api_contracts/gen/feed_v1"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/domain"
+)
+
+// backendSorts are the orders feed service can sort by itself.
+var backendSorts = map[feed.SortBy]domain.SortKey{
+	feed.SortBy_SORT_DATE_DESC:    {Field: "posted_at", Desc: true},
+	feed.SortBy_SORT_DATE_ASC:     {Field: "posted_at"},
+	feed.SortBy_SORT_PRICE_DESC:   {Field: "price", Desc: true},
+	feed.SortBy_SORT_PRICE_ASC:    {Field: "price"},
+	feed.SortBy_SORT_MILEAGE_DESC: {Field: "mileage", Desc: true},
+	feed.SortBy_SORT_MILEAGE_ASC:  {Field: "mileage"},
+}
+
+// ToSort reads sort=price,-year,mileage param, falling back to legacy
+// sort_by=SORT_PRICE_ASC one.
+func ToSort(q url.Values) (domain.Sort, error) {
+	if !q.Has("sort") {
+		return legacySort(q.Get("sort_by"))
+	}
+
+	var (
+		sort domain.Sort
+		errs []error
+	)
+	for _, key := range multi(q, "sort") {
+		k := domain.SortKey{Field: strings.ToLower(strings.TrimPrefix(key, "-")), Desc: strings.HasPrefix(key, "-")}
+		if !domain.IsSortField(k.Field) {
+			errs = append(errs, fmt.Errorf("unknown sort field %q, expected one of %s", k.Field, strings.Join(domain.SortFields(), ", ")))
+			continue
+		}
+		for _, prev := range sort {
+			if prev.Field == k.Field {
+				errs = append(errs, fmt.Errorf("sort field %q is repeated", k.Field))
+			}
+		}
+		sort = append(sort, k)
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return sort, nil
+}
+
+func legacySort(sortBy string) (domain.Sort, error) {
+	sortBy = strings.ToUpper(sortBy)
+	if sortBy == "" || sortBy == feed.SortBy_SORT_UNSPECIFIED.String() {
+		return nil, nil
+	}
+
+	v, ok := feed.SortBy_value[sortBy]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort_by %q", sortBy)
+	}
+	return domain.Sort{backendSorts[feed.SortBy(v)]}, nil
+}
+
+// ToBackendSort maps sort key to the feed service order, if it has one.
+func ToBackendSort(k domain.SortKey) (feed.SortBy, bool) {
+	for sortBy, key := range backendSorts {
+		if key == k {
+			return sortBy, true
+		}
+	}
+	return feed.SortBy_SORT_UNSPECIFIED, false
+}
@@ -0,0 +1,43 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/domain"
+)
+
+func TestFileSinkAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notifications.ndjson")
+	sink, err := NewSinks("file", Options{FilePath: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"n1", "n2"} {
+		if err = sink.Send(context.Background(), &domain.Notification{NotificationId: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var ids []string
+	for scanner := bufio.NewScanner(f); scanner.Scan(); {
+		var n domain.Notification
+		if err = json.Unmarshal(scanner.Bytes(), &n); err != nil {
+			t.Fatalf("bad line %q: %v", scanner.Text(), err)
+		}
+		ids = append(ids, n.NotificationId)
+	}
+	if len(ids) != 2 || ids[0] != "n1" || ids[1] != "n2" {
+		t.Errorf("file has notifications %v, want [n1 n2]", ids)
+	}
+}
@@ -0,0 +1,110 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/domain"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/store"
+)
+
+// Sink delivers notifications to users.
+type Sink interface {
+	Send(ctx context.Context, n *domain.Notification) error
+}
+
+// SinkNames are the sinks NOTIFY_SINKS can list.
+var SinkNames = []string{"log", "inbox", "webhook", "sse", "file"}
+
+type Options struct {
+	Logger        *slog.Logger
+	Inbox         store.Inbox
+	WebhookURL    string
+	WebhookSecret string
+	// Stream gets notifications when sse sink is listed
+	Stream   *Stream
+	FilePath string
+}
+
+// NewSinks builds sinks from a comma separated list of names, delivering to
+// all of them.
+func NewSinks(names string, opts Options) (Sink, error) {
+	var sinks multi
+	for _, name := range strings.Split(names, ",") {
+		switch name = strings.TrimSpace(name); name {
+		case "":
+		case "log":
+			sinks = append(sinks, &logSink{logger: opts.Logger})
+		case "inbox":
+			sinks = append(sinks, &inboxSink{inbox: opts.Inbox})
+		case "webhook":
+			if opts.WebhookURL == "" {
+				return nil, errors.New("webhook sink needs a URL")
+			}
+			sinks = append(sinks, newWebhookSink(opts.WebhookURL, opts.WebhookSecret))
+		case "sse":
+			if opts.Stream == nil {
+				return nil, errors.New("sse sink needs a stream")
+			}
+			sinks = append(sinks, opts.Stream)
+		case "file":
+			if opts.FilePath == "" {
+				return nil, errors.New("file sink needs a path")
+			}
+			sinks = append(sinks, &fileSink{path: opts.FilePath})
+		default:
+			return nil, fmt.Errorf("unknown sink %q, expected %s", name, strings.Join(SinkNames, ", "))
+		}
+	}
+	return sinks, nil
+}
+
+// Listed tells whether sink name is in the comma separated names.
+func Listed(names, name string) bool {
+	for _, n := range strings.Split(names, ",") {
+		if strings.TrimSpace(n) == name {
+			return true
+		}
+	}
+	return false
+}
+
+type multi []Sink
+
+// Send tries every sink, so one failing doesn't hold back the others.
+func (m multi) Send(ctx context.Context, n *domain.Notification) error {
+	var errs []error
+	for _, s := range m {
+		if err := s.Send(ctx, n); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type logSink struct {
+	logger *slog.Logger
+}
+
+func (s *logSink) Send(_ context.Context, n *domain.Notification) error {
+	s.logger.Info("notification",
+		slog.String("id", n.NotificationId),
+		slog.String("user", n.UserId),
+		slog.String("kind", n.Kind),
+		slog.String("subject", n.SubjectId),
+		slog.String("title", n.Title),
+		slog.Int("listings", len(n.Listings)),
+	)
+	return nil
+}
+
+type inboxSink struct {
+	inbox store.Inbox
+}
+
+func (s *inboxSink) Send(ctx context.Context, n *domain.Notification) error {
+	return s.inbox.Add(ctx, n)
+}
@@ -0,0 +1,59 @@
+package notify
+
+import (
+	"context"
+	"sync"
+
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/domain"
+)
+
+// streamBuffer is how many notifications a slow subscriber can lag behind
+// before newer ones are dropped for it.
+const streamBuffer = 16
+
+// Stream fans notifications out to subscribers of their user, e.g.
+// connected SSE clients. Notifications of users with no subscribers are
+// dropped.
+type Stream struct {
+	mu   sync.Mutex
+	subs map[string]map[chan *domain.Notification]struct{}
+}
+
+func NewStream() *Stream {
+	return &Stream{subs: map[string]map[chan *domain.Notification]struct{}{}}
+}
+
+func (s *Stream) Send(_ context.Context, n *domain.Notification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for ch := range s.subs[n.UserId] {
+		select {
+		case ch <- n:
+		default:
+		}
+	}
+	return nil
+}
+
+// Subscribe returns notifications of the user until cancel is called.
+func (s *Stream) Subscribe(userId string) (<-chan *domain.Notification, func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ch := make(chan *domain.Notification, streamBuffer)
+	if s.subs[userId] == nil {
+		s.subs[userId] = map[chan *domain.Notification]struct{}{}
+	}
+	s.subs[userId][ch] = struct{}{}
+
+	return ch, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		delete(s.subs[userId], ch)
+		if len(s.subs[userId]) == 0 {
+			delete(s.subs, userId)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package notify
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/domain"
+)
+
+func TestStreamDeliversToUserSubscribers(t *testing.T) {
+	s := NewStream()
+	first, cancelFirst := s.Subscribe("u1")
+	defer cancelFirst()
+	second, cancelSecond := s.Subscribe("u1")
+	defer cancelSecond()
+	other, cancelOther := s.Subscribe("u2")
+	defer cancelOther()
+
+	n := &domain.Notification{NotificationId: "n1", UserId: "u1"}
+	if err := s.Send(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, ch := range []<-chan *domain.Notification{first, second} {
+		select {
+		case got := <-ch:
+			if got.NotificationId != "n1" {
+				t.Errorf("subscriber %d got %q, want n1", i, got.NotificationId)
+			}
+		default:
+			t.Errorf("subscriber %d got nothing", i)
+		}
+	}
+	select {
+	case got := <-other:
+		t.Errorf("subscriber of u2 got %q of u1", got.NotificationId)
+	default:
+	}
+}
+
+func TestStreamCancel(t *testing.T) {
+	s := NewStream()
+	ch, cancel := s.Subscribe("u1")
+	cancel()
+
+	s.Send(context.Background(), &domain.Notification{UserId: "u1"})
+	select {
+	case <-ch:
+		t.Error("cancelled subscriber got a notification")
+	default:
+	}
+	if len(s.subs) != 0 {
+		t.Errorf("subscribers left after cancel: %v", s.subs)
+	}
+}
+
+func TestStreamDropsForSlowSubscriber(t *testing.T) {
+	s := NewStream()
+	ch, cancel := s.Subscribe("u1")
+	defer cancel()
+
+	// Send must not block on a subscriber which doesn't read
+	for range streamBuffer + 5 {
+		s.Send(context.Background(), &domain.Notification{UserId: "u1"})
+	}
+	if len(ch) != streamBuffer {
+		t.Errorf("buffered %d notifications, want %d", len(ch), streamBuffer)
+	}
+}
@@ -0,0 +1,66 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/domain"
+)
+
+// webhookSink POSTs notifications as JSON. With a secret the body is signed
+// with HMAC-SHA256 of "<timestamp>.<body>", sent in X-Webhook-Timestamp and
+// X-Webhook-Signature headers, so receivers can check it and reject replays.
+type webhookSink struct {
+	url    string
+	secret []byte
+	client *http.Client
+}
+
+func newWebhookSink(url, secret string) *webhookSink {
+	return &webhookSink{url: url, secret: []byte(secret), client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Sign returns X-Webhook-Signature value of body sent at timestamp.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *webhookSink) Send(ctx context.Context, n *domain.Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Notification-Id", n.NotificationId)
+	if len(s.secret) > 0 {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set("X-Webhook-Timestamp", timestamp)
+		req.Header.Set("X-Webhook-Signature", Sign(s.secret, timestamp, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook: %s answered %s", s.url, resp.Status)
+	}
+	return nil
+}
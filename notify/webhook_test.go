@@ -0,0 +1,66 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/domain"
+)
+
+func TestSign(t *testing.T) {
+	// echo -n '1700000000.{"a":1}' | openssl dgst -sha256 -hmac secret
+	const want = "sha256=49f24e537407743fa4a0242bb63b94b9a47ee99cbbe071ccd8a22550ae411686"
+	got := Sign([]byte("secret"), "1700000000", []byte(`{"a":1}`))
+	if got != want {
+		t.Errorf("Sign = %s, want %s", got, want)
+	}
+	if Sign([]byte("secret"), "1700000001", []byte(`{"a":1}`)) == got {
+		t.Error("signature doesn't depend on timestamp")
+	}
+	if Sign([]byte("other"), "1700000000", []byte(`{"a":1}`)) == got {
+		t.Error("signature doesn't depend on secret")
+	}
+}
+
+func TestWebhookSinkSigns(t *testing.T) {
+	var (
+		body   []byte
+		header http.Header
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		header = r.Header
+	}))
+	defer srv.Close()
+
+	n := &domain.Notification{NotificationId: "n1", UserId: "u1", Title: "Price dropped"}
+	if err := newWebhookSink(srv.URL, "secret").Send(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+
+	var got domain.Notification
+	if err := json.Unmarshal(body, &got); err != nil || got.NotificationId != "n1" {
+		t.Errorf("webhook got %s, want notification n1", body)
+	}
+	if header.Get("X-Notification-Id") != "n1" {
+		t.Errorf("X-Notification-Id = %q, want n1", header.Get("X-Notification-Id"))
+	}
+	if want := Sign([]byte("secret"), header.Get("X-Webhook-Timestamp"), body); header.Get("X-Webhook-Signature") != want {
+		t.Errorf("X-Webhook-Signature = %q, want %q", header.Get("X-Webhook-Signature"), want)
+	}
+}
+
+func TestWebhookSinkFails(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	if err := newWebhookSink(srv.URL, "").Send(context.Background(), &domain.Notification{}); err == nil {
+		t.Error("Send succeeded, receiver answered 500")
+	}
+}
@@ -0,0 +1,85 @@
+package pagination
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// Cursor points at the listing a page started or ended with, keeping its
+// values of sort fields. Offset and Total are positions in backend order when
+// cursor was made, used only as a hint where to start looking for the listing.
+type Cursor struct {
+	Keys      map[string]json.RawMessage `json:"k,omitempty"`
+	ListingId string                     `json:"i"`
+	Backward  bool                       `json:"b,omitempty"`
+	Offset    int32                      `json:"o"`
+	Total     int32                      `json:"c"`
+	Scope     string                     `json:"q"`
+}
+
+var ErrBadCursor = errors.New("cursor is malformed or was issued for another query")
+
+// Codec signs cursors, so clients can't forge positions. With empty secret
+// a random one is used and cursors become invalid after restart.
+type Codec struct {
+	secret []byte
+}
+
+func NewCodec(secret string) *Codec {
+	key := []byte(secret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		rand.Read(key)
+	}
+	return &Codec{secret: key}
+}
+
+func (c *Codec) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)[:16]
+}
+
+func (c *Codec) Encode(cur *Cursor) string {
+	payload, _ := json.Marshal(cur)
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(c.sign(payload))
+}
+
+func (c *Codec) Decode(token, scope string) (*Cursor, error) {
+	enc := base64.RawURLEncoding
+
+	rawPayload, rawSig, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrBadCursor
+	}
+	payload, err := enc.DecodeString(rawPayload)
+	if err != nil {
+		return nil, ErrBadCursor
+	}
+	sig, err := enc.DecodeString(rawSig)
+	if err != nil || !hmac.Equal(sig, c.sign(payload)) {
+		return nil, ErrBadCursor
+	}
+
+	cur := &Cursor{}
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.DisallowUnknownFields()
+	if err = dec.Decode(cur); err != nil || cur.Scope != scope {
+		return nil, ErrBadCursor
+	}
+	return cur, nil
+}
+
+// Scope fingerprints the query a cursor belongs to.
+func Scope(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:8])
+}
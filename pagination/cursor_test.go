@@ -0,0 +1,68 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCodecRoundTrip(t *testing.T) {
+	c := NewCodec("secret")
+	cur := &Cursor{
+		Keys:      map[string]json.RawMessage{"price": json.RawMessage("1500")},
+		ListingId: "l-42",
+		Backward:  true,
+		Offset:    41,
+		Total:     100,
+		Scope:     Scope("/feed/listings", "sort=price"),
+	}
+
+	got, err := c.Decode(c.Encode(cur), cur.Scope)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if !reflect.DeepEqual(got, cur) {
+		t.Errorf("Decode = %+v, want %+v", got, cur)
+	}
+}
+
+func TestCodecRejects(t *testing.T) {
+	c := NewCodec("secret")
+	scope := Scope("/feed/listings", "")
+	token := c.Encode(&Cursor{ListingId: "l-1", Offset: 9, Scope: scope})
+	payload, sig, _ := strings.Cut(token, ".")
+
+	forged, _ := json.Marshal(&Cursor{ListingId: "l-1", Offset: 90, Scope: scope})
+	unknown, _ := json.Marshal(map[string]any{"i": "l-1", "q": scope, "x": 1})
+	sign := func(payload []byte) string {
+		return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(c.sign(payload))
+	}
+
+	tests := []struct {
+		name, token, scope string
+	}{
+		{"no signature", payload, scope},
+		{"bad base64", "!!." + sig, scope},
+		{"forged payload", base64.RawURLEncoding.EncodeToString(forged) + "." + sig, scope},
+		{"other secret", NewCodec("other").Encode(&Cursor{ListingId: "l-1", Scope: scope}), scope},
+		{"other query", token, Scope("/feed/listings", "make=bmw")},
+		{"unknown field", sign(unknown), scope},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := c.Decode(tt.token, tt.scope); !errors.Is(err, ErrBadCursor) {
+				t.Errorf("Decode error = %v, want ErrBadCursor", err)
+			}
+		})
+	}
+}
+
+func TestCodecRandomSecret(t *testing.T) {
+	cur := &Cursor{ListingId: "l-1"}
+	if _, err := NewCodec("").Decode(NewCodec("").Encode(cur), ""); err == nil {
+		t.Error("cursor of one random secret codec is accepted by another")
+	}
+}
@@ -0,0 +1,31 @@
+package pagination
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// PageURL is request URL with params replaced (or removed when value is empty).
+func PageURL(r *http.Request, params map[string]string) string {
+	q := r.URL.Query()
+	for k, v := range params {
+		if v == "" {
+			q.Del(k)
+		} else {
+			q.Set(k, v)
+		}
+	}
+	u := url.URL{Path: r.URL.Path, RawQuery: q.Encode()}
+	return u.String()
+}
+
+// LinkHeader formats RFC 5988 links given as rel, URL pairs.
+func LinkHeader(links [][2]string) string {
+	parts := make([]string, 0, len(links))
+	for _, l := range links {
+		parts = append(parts, fmt.Sprintf("<%s>; rel=%q", l[1], l[0]))
+	}
+	return strings.Join(parts, ", ")
+}
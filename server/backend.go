@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Backend is a gRPC connection which can be switched to another address
+// at runtime. Calls in flight keep using the old connection, which is closed
+// after drainTimeout.
+type Backend struct {
+	name         string
+	mu           sync.RWMutex
+	addr         string
+	conn         *grpc.ClientConn
+	drainTimeout time.Duration
+	logger       *slog.Logger
+}
+
+func NewBackend(name, addr string, logger *slog.Logger) (*Backend, error) {
+	conn, err := Connect(addr)
+	if err != nil {
+		return nil, err
+	}
+	return &Backend{
+		name:         name,
+		addr:         addr,
+		conn:         conn,
+		drainTimeout: time.Minute,
+		logger:       logger,
+	}, nil
+}
+
+func (b *Backend) current() *grpc.ClientConn {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.conn
+}
+
+func (b *Backend) Addr() string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.addr
+}
+
+func (b *Backend) Invoke(ctx context.Context, method string, args, reply any, opts ...grpc.CallOption) error {
+	return b.current().Invoke(ctx, method, args, reply, opts...)
+}
+
+func (b *Backend) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return b.current().NewStream(ctx, desc, method, opts...)
+}
+
+func (b *Backend) SetAddr(addr string) error {
+	if addr == b.Addr() {
+		return nil
+	}
+
+	cc, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	old := b.conn
+	b.conn, b.addr = cc, addr
+	b.mu.Unlock()
+
+	b.logger.Info("Backend address changed", slog.String("backend", b.name), slog.String("addr", addr))
+	time.AfterFunc(b.drainTimeout, func() {
+		old.Close()
+	})
+	return nil
+}
@@ -0,0 +1,202 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	feed "github.com/nikita-itmo-gh-acc/car_estimator_api_contracts/gen/feed_v1"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/domain"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/mappers"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/utils"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	bulkJobTTL    = time.Hour
+	bulkBodyLimit = 64 << 20
+)
+
+var errTooManyRows = errors.New("too many rows")
+
+// bulkImporter creates listings of bulk imports and keeps their jobs, finished
+// ones for bulkJobTTL.
+type bulkImporter struct {
+	asyncRows int
+	maxRows   int
+	jobs      *jobTracker[domain.BulkRowResult]
+}
+
+func newBulkImporter(concurrency, asyncRows, maxRows int) *bulkImporter {
+	return &bulkImporter{
+		asyncRows: asyncRows,
+		maxRows:   maxRows,
+		jobs:      newJobTracker[domain.BulkRowResult](concurrency, bulkJobTTL),
+	}
+}
+
+// bulkRow is a parsed row, err is set when it can't be imported.
+type bulkRow struct {
+	listing *domain.CarListing
+	err     error
+}
+
+// parse reads CSV with a header row or NDJSON body, validating every row.
+func (b *bulkImporter) parse(w http.ResponseWriter, r *http.Request) ([]bulkRow, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	body := http.MaxBytesReader(w, r.Body, bulkBodyLimit)
+
+	var rows []bulkRow
+	add := func(l *domain.CarListing, err error) error {
+		if len(rows) == b.maxRows {
+			return errTooManyRows
+		}
+		if err == nil {
+			err = l.Validate()
+		}
+		rows = append(rows, bulkRow{l, err})
+		return nil
+	}
+
+	switch mediaType {
+	case "text/csv":
+		reader := csv.NewReader(body)
+		header, err := reader.Read()
+		if err != nil {
+			return nil, fmt.Errorf("can't read CSV header: %w", err)
+		}
+		columns, err := mappers.ToCSVColumns(header)
+		if err != nil {
+			return nil, err
+		}
+
+		for {
+			record, err := reader.Read()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			var l *domain.CarListing
+			switch {
+			case errors.Is(err, csv.ErrFieldCount):
+			case err != nil:
+				return nil, fmt.Errorf("can't read CSV: %w", err)
+			default:
+				l, err = mappers.ToListingFromCSV(columns, record)
+			}
+			if err = add(l, err); err != nil {
+				return nil, err
+			}
+		}
+
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		scanner := bufio.NewScanner(body)
+		scanner.Buffer(nil, 1<<20)
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+
+			l := &domain.CarListing{}
+			dec := json.NewDecoder(bytes.NewReader(line))
+			dec.DisallowUnknownFields()
+			err := dec.Decode(l)
+			if err != nil {
+				err = fmt.Errorf("bad JSON: %w", err)
+			}
+			if err = add(l, err); err != nil {
+				return nil, err
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("can't read NDJSON: %w", err)
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported content type %q, expected text/csv or application/x-ndjson", mediaType)
+	}
+
+	if len(rows) == 0 {
+		return nil, errors.New("no rows to import")
+	}
+	return rows, nil
+}
+
+// run creates listings of valid rows, at most concurrency at a time.
+// created, if set, sees every created listing.
+func (b *bulkImporter) run(ctx context.Context, client feed.FeedServiceClient, id string, rows []bulkRow, created func(l *domain.CarListing)) {
+	b.jobs.run(ctx, id, func(ctx context.Context, i int) (domain.BulkRowResult, bool) {
+		// rows are numbered from 1, not counting CSV header
+		res := domain.BulkRowResult{Row: i + 1}
+		if rows[i].err != nil {
+			res.Error = rows[i].err.Error()
+			return res, false
+		}
+
+		resp, err := client.CreateListing(ctx, &feed.CreateListingRequest{Listing: mappers.ToMessage(rows[i].listing)})
+		if err != nil {
+			res.Error = status.Convert(err).Message()
+			return res, false
+		}
+		res.ListingId = resp.GetListing().GetListingId()
+		if created != nil {
+			created(mappers.ToDomain(resp.GetListing()))
+		}
+		return res, true
+	})
+}
+
+// ImportListings creates listings from CSV or NDJSON body. Imports larger than
+// BULK_ASYNC_ROWS rows, or any with async=true, run in background and are
+// answered with 202 and the job location.
+func (h *FeedHandler) ImportListings(w http.ResponseWriter, r *http.Request) {
+	log := h.logger.With(slog.String("op", "ImportListings"))
+
+	rows, err := h.bulk.parse(w, r)
+	var maxBytes *http.MaxBytesError
+	switch {
+	case errors.Is(err, errTooManyRows), errors.As(err, &maxBytes):
+		http.Error(w, fmt.Sprintf("import is too large, at most %d rows are allowed: %v", h.bulk.maxRows, err), http.StatusRequestEntityTooLarge)
+		return
+	case err != nil:
+		http.Error(w, "bad import: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id := h.bulk.jobs.start(len(rows))
+	log.Info("→ gRPC CreateListing for bulk import", slog.String("job", id), slog.Int("rows", len(rows)))
+
+	if r.URL.Query().Get("async") == "true" || len(rows) > h.bulk.asyncRows {
+		go h.bulk.run(context.WithoutCancel(r.Context()), h.client, id, rows, h.publishCreated)
+
+		job, _ := h.bulk.jobs.get(id)
+		w.Header().Set("Location", r.URL.Path+"/"+id)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(job)
+		return
+	}
+
+	h.bulk.run(r.Context(), h.client, id, rows, h.publishCreated)
+	job, _ := h.bulk.jobs.get(id)
+	utils.RenderJson(w, job)
+}
+
+func (h *FeedHandler) GetImportJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.bulk.jobs.get(mux.Vars(r)["jobId"])
+	if !ok {
+		http.Error(w, "import job not found", http.StatusNotFound)
+		return
+	}
+	utils.RenderJson(w, job)
+}
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+
+	feed "github.com/nikita-itmo-gh-acc/car_estimator_api_contracts/gen/feed_v1"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/domain"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/mappers"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/pagination"
+)
+
+func newCursor(l *domain.CarListing, offset, total int32, sort domain.Sort, backward bool, scope string) *pagination.Cursor {
+	cur := &pagination.Cursor{
+		ListingId: l.ListingId,
+		Backward:  backward,
+		Offset:    offset,
+		Total:     total,
+		Scope:     scope,
+	}
+
+	if len(sort) > 0 {
+		var fields map[string]json.RawMessage
+		raw, _ := json.Marshal(l)
+		json.Unmarshal(raw, &fields)
+
+		cur.Keys = make(map[string]json.RawMessage, len(sort))
+		for _, k := range sort {
+			cur.Keys[k.Field] = fields[k.Field]
+		}
+	}
+	return cur
+}
+
+// cursorListing restores sort fields of the listing cursor points at.
+func cursorListing(cur *pagination.Cursor) *domain.CarListing {
+	l := &domain.CarListing{}
+	raw, _ := json.Marshal(cur.Keys)
+	json.Unmarshal(raw, l)
+	l.ListingId = cur.ListingId
+	return l
+}
+
+// cursorPage is a page read after (or before, for backward cursors) a cursor.
+type cursorPage struct {
+	listings  []positioned
+	more      bool
+	total     int32
+	truncated bool
+}
+
+// walkFromCursor reads listings following the cursor position. Listings
+// ordered before the cursor are skipped, so items inserted or removed since
+// the previous page don't produce duplicates or gaps. Backward cursors walk
+// the reversed order and flip the result. Without a sort order the cursor
+// listing is looked up by id, falling back to its old offset.
+func (h *FeedHandler) walkFromCursor(ctx context.Context, fetch pageFetcher, order listingOrder, cur *pagination.Cursor, filter *domain.ListingFilter, pageSize int) (*cursorPage, error) {
+	passed := false
+	start := cur.Offset
+	if cur.Backward {
+		order = order.reversed()
+		start = cur.Total - cur.Offset - 1
+	}
+	at := cursorListing(cur)
+
+	// start one page early and step back while the page could still hold
+	// listings ordered after the cursor, they could move up since it was made.
+	// Order made on the gateway entirely needs all the listings.
+	pageNum := int32(1)
+	if order.grouped > 0 || !order.sorted() {
+		pageNum = max(1, max(start, 0)/scanPageSize)
+	}
+	for order.grouped > 0 && pageNum > 1 {
+		listings, _, err := fetch(ctx, &feed.PageRequest{PageNumber: pageNum, PageSize: scanPageSize}, order.backend)
+		if err != nil {
+			return nil, err
+		}
+		if len(listings) > 0 && order.compareGroups(mappers.ToDomain(listings[0]), at) < 0 {
+			break
+		}
+		pageNum--
+	}
+
+	var (
+		res     = &cursorPage{}
+		skipped []positioned
+	)
+
+	emit := func(p positioned) bool {
+		if filter.Match(&p.listing) {
+			res.listings = append(res.listings, p)
+		}
+		// one extra listing tells there is a next page
+		return len(res.listings) <= pageSize
+	}
+
+	truncated, total, err := stream(ctx, fetch, order, pageNum, h.scanLimit, func(p positioned) bool {
+		switch {
+		case passed:
+			return emit(p)
+		case order.sorted():
+			if order.compare(&p.listing, at) <= 0 {
+				return true
+			}
+			passed = true
+			return emit(p)
+		case p.listing.ListingId == cur.ListingId:
+			passed, skipped = true, nil
+		default:
+			skipped = append(skipped, p)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	res.total, res.truncated = total, truncated
+
+	if !passed {
+		for _, p := range skipped {
+			if p.offset > cur.Offset && !emit(p) {
+				break
+			}
+		}
+	}
+
+	if len(res.listings) > pageSize {
+		res.listings, res.more = res.listings[:pageSize], true
+	}
+	if cur.Backward {
+		slices.Reverse(res.listings)
+		for i := range res.listings {
+			res.listings[i].offset = total - res.listings[i].offset - 1
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,231 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+
+	feed "github.com/nikita-itmo-gh-acc/car_estimator_api_contracts/gen/feed_v1"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/domain"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/pagination"
+)
+
+// fakeFeed serves listings in pages like the feed service does, sorting by
+// price only. Listings of equal price come in the same shuffled order in both
+// directions, so descending order isn't ascending one reversed.
+type fakeFeed struct {
+	listings []*feed.CarListing
+	shuffled []*feed.CarListing
+}
+
+func newFakeFeed(n int) *fakeFeed {
+	f := &fakeFeed{}
+	for i := range n {
+		f.listings = append(f.listings, &feed.CarListing{
+			ListingId: fmt.Sprintf("l-%03d", i),
+			Price:     float64(1000 * (i % 7)),
+		})
+	}
+	f.shuffled = slices.Clone(f.listings)
+	rand.New(rand.NewSource(1)).Shuffle(n, func(i, j int) { f.shuffled[i], f.shuffled[j] = f.shuffled[j], f.shuffled[i] })
+	return f
+}
+
+func (f *fakeFeed) fetch(_ context.Context, page *feed.PageRequest, sortBy feed.SortBy) ([]*feed.CarListing, *feed.PageResponseMetadata, error) {
+	all := slices.Clone(f.listings)
+	if sortBy == feed.SortBy_SORT_PRICE_ASC || sortBy == feed.SortBy_SORT_PRICE_DESC {
+		all = slices.Clone(f.shuffled)
+		slices.SortStableFunc(all, func(a, b *feed.CarListing) int {
+			if sortBy == feed.SortBy_SORT_PRICE_DESC {
+				return cmp.Compare(b.Price, a.Price)
+			}
+			return cmp.Compare(a.Price, b.Price)
+		})
+	}
+
+	size := int(page.PageSize)
+	from := min(int(page.PageNumber-1)*size, len(all))
+	to := min(from+size, len(all))
+	return all[from:to], &feed.PageResponseMetadata{
+		TotalItems:  int32(len(all)),
+		TotalPages:  int32((len(all) + size - 1) / size),
+		CurrentPage: page.PageNumber,
+	}, nil
+}
+
+func (f *fakeFeed) remove(id string) {
+	gone := func(c *feed.CarListing) bool { return c.ListingId == id }
+	f.listings = slices.DeleteFunc(f.listings, gone)
+	f.shuffled = slices.DeleteFunc(f.shuffled, gone)
+}
+
+func ids(items []positioned) []string {
+	res := make([]string, len(items))
+	for i, p := range items {
+		res[i] = p.listing.ListingId
+	}
+	return res
+}
+
+// sortedIds are ids of listings in order.
+func (f *fakeFeed) sortedIds(order listingOrder) []string {
+	var res []string
+	for _, c := range f.listings {
+		res = append(res, c.ListingId)
+	}
+	byId := map[string]*feed.CarListing{}
+	for _, c := range f.listings {
+		byId[c.ListingId] = c
+	}
+	slices.SortFunc(res, func(a, b string) int {
+		return order.compare(&domain.CarListing{ListingId: a, Price: byId[a].Price}, &domain.CarListing{ListingId: b, Price: byId[b].Price})
+	})
+	return res
+}
+
+func at(f *fakeFeed, id string) *domain.CarListing {
+	for _, c := range f.listings {
+		if c.ListingId == id {
+			return &domain.CarListing{ListingId: c.ListingId, Price: c.Price}
+		}
+	}
+	return &domain.CarListing{ListingId: id}
+}
+
+func TestWalkFromCursorPagesThroughTies(t *testing.T) {
+	const pageSize = 20
+	f := newFakeFeed(250)
+	h := &FeedHandler{scanLimit: 1000}
+
+	for _, sort := range []domain.Sort{{{Field: "price"}}, {{Field: "price", Desc: true}}} {
+		t.Run(sort.String(), func(t *testing.T) {
+			order := newListingOrder(sort)
+			want := f.sortedIds(order)
+
+			got := slices.Clone(want[:pageSize])
+			cur := newCursor(at(f, got[len(got)-1]), pageSize-1, 250, sort, false, "")
+			for {
+				res, err := h.walkFromCursor(context.Background(), f.fetch, order, cur, nil, pageSize)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, ids(res.listings)...)
+				if !res.more {
+					break
+				}
+				last := res.listings[len(res.listings)-1]
+				cur = newCursor(&last.listing, last.offset, res.total, sort, false, "")
+			}
+
+			if !slices.Equal(got, want) {
+				t.Errorf("walked %d listings, want %d in sort order\ngot  %v\nwant %v", len(got), len(want), got, want)
+			}
+		})
+	}
+}
+
+func TestWalkFromCursorBackward(t *testing.T) {
+	const pageSize = 20
+	f := newFakeFeed(250)
+	h := &FeedHandler{scanLimit: 1000}
+	sort := domain.Sort{{Field: "price"}}
+	order := newListingOrder(sort)
+	want := f.sortedIds(order)
+
+	// page 5 starts at want[80], previous is page 4
+	cur := newCursor(at(f, want[80]), 80, 250, sort, true, "")
+	res, err := h.walkFromCursor(context.Background(), f.fetch, order, cur, nil, pageSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(res.listings); !slices.Equal(got, want[60:80]) {
+		t.Errorf("previous page = %v, want %v", got, want[60:80])
+	}
+	if !res.more {
+		t.Error("more = false, pages 1-3 are before it")
+	}
+}
+
+func TestWalkFromCursorSkipsRemoved(t *testing.T) {
+	const pageSize = 10
+	f := newFakeFeed(50)
+	h := &FeedHandler{scanLimit: 1000}
+	sort := domain.Sort{{Field: "price"}}
+	order := newListingOrder(sort)
+	want := f.sortedIds(order)
+
+	cur := newCursor(at(f, want[9]), 9, 50, sort, false, "")
+	// the cursor listing and one on the previous page are gone
+	f.remove(want[9])
+	f.remove(want[3])
+
+	res, err := h.walkFromCursor(context.Background(), f.fetch, order, cur, nil, pageSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(res.listings); !slices.Equal(got, want[10:20]) {
+		t.Errorf("next page = %v, want %v", got, want[10:20])
+	}
+}
+
+func TestWalkFromCursorUnsorted(t *testing.T) {
+	const pageSize = 10
+	f := newFakeFeed(50)
+	h := &FeedHandler{scanLimit: 1000}
+	order := newListingOrder(nil)
+
+	cur := &pagination.Cursor{ListingId: "l-009", Offset: 9, Total: 50}
+	res, err := h.walkFromCursor(context.Background(), f.fetch, order, cur, nil, pageSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(res.listings); got[0] != "l-010" || len(got) != pageSize {
+		t.Errorf("next page = %v, want 10 listings from l-010", got)
+	}
+
+	// without the cursor listing its offset is used
+	f.remove("l-009")
+	res, err = h.walkFromCursor(context.Background(), f.fetch, order, cur, nil, pageSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(res.listings); got[0] != "l-011" {
+		t.Errorf("next page after removal = %v, want it from l-011, the listing at offset 10", got)
+	}
+}
+
+func TestWalkFromCursorFilterAndLimit(t *testing.T) {
+	f := newFakeFeed(250)
+	sort := domain.Sort{{Field: "price"}}
+	order := newListingOrder(sort)
+	want := f.sortedIds(order)
+	price := 3000.0
+	filter := &domain.ListingFilter{PriceMin: &price, PriceMax: &price}
+
+	h := &FeedHandler{scanLimit: 1000}
+	cur := newCursor(at(f, want[0]), 0, 250, sort, false, "")
+	res, err := h.walkFromCursor(context.Background(), f.fetch, order, cur, filter, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range res.listings {
+		if p.listing.Price != price {
+			t.Fatalf("listing %s of price %v passed filter", p.listing.ListingId, p.listing.Price)
+		}
+	}
+	if len(res.listings) != 36 || res.more || res.truncated {
+		t.Errorf("got %d listings, more %v, truncated %v, want all 36 of price 3000", len(res.listings), res.more, res.truncated)
+	}
+
+	h.scanLimit = 50
+	res, err = h.walkFromCursor(context.Background(), f.fetch, order, cur, filter, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.truncated || len(res.listings) != 0 {
+		t.Errorf("got %d listings, truncated %v, want scan cut before price 3000", len(res.listings), res.truncated)
+	}
+}
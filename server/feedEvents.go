@@ -0,0 +1,168 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/domain"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/mappers"
+)
+
+// eventSubBuffer is how many events a subscriber can lag behind. Slower
+// ones are disconnected and resume from Last-Event-ID.
+const eventSubBuffer = 64
+
+// listingEvents keeps recent listing changes made through the gateway and
+// fans them out to stream subscribers. Event ids are "<epoch>-<seq>", epoch
+// telling ids of a previous process apart.
+type listingEvents struct {
+	mu     sync.Mutex
+	epoch  string
+	seq    uint64
+	size   int
+	recent []domain.ListingEvent
+	subs   map[chan domain.ListingEvent]struct{}
+}
+
+func newListingEvents(size int) *listingEvents {
+	return &listingEvents{
+		epoch: strconv.FormatInt(time.Now().UnixNano(), 36),
+		size:  size,
+		subs:  map[chan domain.ListingEvent]struct{}{},
+	}
+}
+
+func (e *listingEvents) publish(typ, listingId string, l *domain.CarListing) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.seq++
+	ev := domain.ListingEvent{
+		EventId:   fmt.Sprintf("%s-%d", e.epoch, e.seq),
+		Type:      typ,
+		ListingId: listingId,
+		Listing:   l,
+		At:        time.Now().UTC(),
+	}
+	e.recent = append(e.recent, ev)
+	if len(e.recent) > e.size {
+		e.recent = e.recent[len(e.recent)-e.size:]
+	}
+
+	for ch := range e.subs {
+		select {
+		case ch <- ev:
+		default:
+			delete(e.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+func (h *FeedHandler) publishCreated(l *domain.CarListing) {
+	h.changes.publish(domain.ListingCreated, l.ListingId, l)
+}
+
+// subscribe returns events following lastId, empty for only new ones, and
+// the channel of later events, closed if the subscriber falls behind.
+// ok is false when events since lastId aren't kept anymore.
+func (e *listingEvents) subscribe(lastId string) (replay []domain.ListingEvent, ok bool, ch chan domain.ListingEvent, cancel func()) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	ok = true
+	if lastId != "" {
+		epoch, rawSeq, _ := strings.Cut(lastId, "-")
+		seq, err := strconv.ParseUint(rawSeq, 10, 64)
+		oldest := e.seq - uint64(len(e.recent))
+
+		switch {
+		case err != nil || epoch != e.epoch || seq > e.seq || seq < oldest:
+			ok = false
+		default:
+			replay = append(replay, e.recent[len(e.recent)-int(e.seq-seq):]...)
+		}
+	}
+
+	ch = make(chan domain.ListingEvent, eventSubBuffer)
+	e.subs[ch] = struct{}{}
+	return replay, ok, ch, func() {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+
+		if _, subscribed := e.subs[ch]; subscribed {
+			delete(e.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// StreamListings sends created, updated and deleted listing events as
+// Server-Sent Events. Only changes made through this gateway are seen, as
+// feed service has no streaming RPC. Filter params apply to created and
+// updated events, deleted ones are always sent. Clients resume with
+// Last-Event-ID header or last_event_id param, and get a reset event when
+// the events they missed aren't kept anymore.
+func (h *FeedHandler) StreamListings(w http.ResponseWriter, r *http.Request) {
+	log := h.logger.With(slog.String("op", "StreamListings"))
+
+	filter, err := mappers.ToListingFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, "bad filter: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	lastId := r.Header.Get("Last-Event-ID")
+	if lastId == "" {
+		lastId = r.URL.Query().Get("last_event_id")
+	}
+	replay, ok, events, cancel := h.changes.subscribe(lastId)
+	defer cancel()
+
+	sse, err := newSSEWriter(w)
+	if err != nil {
+		return
+	}
+	log.Info("listing stream opened", slog.String("last_event_id", lastId), slog.Int("replay", len(replay)))
+
+	send := func(ev domain.ListingEvent) error {
+		if ev.Type != domain.ListingDeleted && !filter.Match(ev.Listing) {
+			return nil
+		}
+		return sse.Event(ev.EventId, ev.Type, ev)
+	}
+
+	if !ok {
+		err = sse.Event("", "reset", domain.StreamReset{Reason: "events since " + lastId + " aren't kept anymore"})
+	}
+	for _, ev := range replay {
+		if err != nil {
+			return
+		}
+		err = send(ev)
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+
+	ctx := untimedContext(r)
+	for err == nil {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			err = sse.Heartbeat()
+		case ev, open := <-events:
+			if !open {
+				log.Info("listing stream subscriber fell behind, disconnecting")
+				return
+			}
+			err = send(ev)
+		}
+	}
+}
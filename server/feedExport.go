@@ -0,0 +1,204 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/blobs"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/domain"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/export"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/mappers"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/utils"
+)
+
+const (
+	exportFlushRows        = 100
+	exportTruncatedTrailer = "X-Export-Truncated"
+)
+
+// ExportListings streams listings of a list query, or a search one when query
+// param is set, with the same filters and sort as CSV, NDJSON or XLSX. Output
+// stops at exportRows rows. Sorts whose first key the backend can't sort by
+// are made over the first SCAN_LIMIT listings only. Exports cut at either
+// limit carry X-Export-Truncated: true trailer. With delivery=link the export is put to
+// the blob store instead, answered with a download URL valid for
+// EXPORT_LINK_TTL, and truncated is set in the answer.
+func (h *FeedHandler) ExportListings(w http.ResponseWriter, r *http.Request) {
+	log := h.logger.With(slog.String("op", "ExportListings"))
+
+	q := r.URL.Query()
+	formatName := q.Get("format")
+	if formatName == "" {
+		formatName = "csv"
+	}
+	format, ok := export.Formats[formatName]
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown format %q, expected csv, ndjson or xlsx", formatName), http.StatusBadRequest)
+		return
+	}
+
+	link := q.Get("delivery") == "link"
+	if !link && q.Has("delivery") && q.Get("delivery") != "stream" {
+		http.Error(w, fmt.Sprintf("unknown delivery %q, expected stream or link", q.Get("delivery")), http.StatusBadRequest)
+		return
+	}
+
+	fetch, route := h.listPages, "list"
+	if q.Has("query") {
+		fetch, route = h.searchPages(q.Get("query")), "search"
+	}
+
+	columns, ok := h.fieldSet(w, r, route)
+	if !ok {
+		return
+	}
+	if columns == nil {
+		columns = domain.StoredListingFields
+	}
+	sort, err := mappers.ToSort(q)
+	if err != nil {
+		http.Error(w, "bad sort: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter, err := mappers.ToListingFilter(q)
+	if err != nil {
+		http.Error(w, "bad filter: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	order := newListingOrder(sort)
+	// backend order is refined in runs of equal first key, without it all
+	// the listings are sorted at once
+	limit := math.MaxInt
+	if order.sorted() && order.grouped == 0 {
+		limit = h.scanLimit
+	}
+
+	var (
+		out       export.Writer
+		rows      int
+		capped    bool
+		writeErr  error
+		favorites = slices.Contains(columns, "is_favorite")
+		spool     *os.File
+	)
+	// link exports are spooled to a temporary file, not kept in memory
+	defer func() {
+		if spool != nil {
+			spool.Close()
+			os.Remove(spool.Name())
+		}
+	}()
+	start := func() (err error) {
+		if link {
+			if spool, err = os.CreateTemp("", "listings-export-*"); err != nil {
+				return err
+			}
+			out, err = export.NewWriter(formatName, spool, columns)
+			return err
+		}
+		w.Header().Set("Content-Type", format.ContentType)
+		w.Header().Set("Content-Disposition", `attachment; filename="listings.`+format.Extension+`"`)
+		w.Header().Set("Trailer", exportTruncatedTrailer)
+		out, err = export.NewWriter(formatName, w, columns)
+		return err
+	}
+
+	log.Info("→ gRPC "+route+" pages for export", slog.String("format", formatName), slog.Any("filter", filter), slog.String("sort", sort.String()))
+	rc := http.NewResponseController(w)
+	truncated, _, err := stream(untimedContext(r), fetch, order, 1, limit, func(p positioned) bool {
+		if !filter.Match(&p.listing) {
+			return true
+		}
+		// a match past exportRows tells the export is cut, not just complete
+		if rows == h.exportRows {
+			capped = true
+			return false
+		}
+		if out == nil {
+			if writeErr = start(); writeErr != nil {
+				return false
+			}
+		}
+		if favorites {
+			listing := []domain.CarListing{p.listing}
+			h.markFavorites(r, listing)
+			p.listing = listing[0]
+		}
+		if writeErr = out.Write(mappers.ToExportRow(&p.listing, columns)); writeErr != nil {
+			return false
+		}
+
+		rows++
+		if !link && rows%exportFlushRows == 0 {
+			rc.Flush()
+		}
+		return true
+	})
+	if err == nil {
+		err = writeErr
+	}
+	truncated = truncated || capped
+
+	switch {
+	case err != nil && (out == nil || link):
+		utils.HandleResponseErr(w, h.logger, "ExportListings failed: ", err)
+		return
+	case err != nil:
+		// status is sent already, broken connection tells the client export failed
+		log.Error("export aborted", slog.Int("rows", rows), slog.Any("error", err))
+		panic(http.ErrAbortHandler)
+	case out == nil:
+		if err = start(); err != nil {
+			utils.HandleResponseErr(w, h.logger, "ExportListings failed: ", err)
+			return
+		}
+	}
+
+	err = out.Close()
+	switch {
+	case err != nil && link:
+		utils.HandleResponseErr(w, h.logger, "ExportListings failed: ", err)
+	case err != nil:
+		log.Error("export aborted", slog.Int("rows", rows), slog.Any("error", err))
+		panic(http.ErrAbortHandler)
+	case link:
+		h.deliverExport(w, r, spool, format, rows, truncated)
+	case truncated:
+		w.Header().Set(exportTruncatedTrailer, "true")
+	}
+}
+
+// deliverExport puts export spooled to file to the blob store and answers with
+// its URL.
+func (h *FeedHandler) deliverExport(w http.ResponseWriter, r *http.Request, file *os.File, format export.Format, rows int, truncated bool) {
+	ctx := untimedContext(r)
+	key := "exports/" + uuid.NewString() + "/listings." + format.Extension
+	expiresAt := time.Now().Add(h.exportLinkTTL)
+
+	size, err := file.Seek(0, io.SeekEnd)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err == nil {
+		err = blobs.PutReader(ctx, h.blobs, key, file, size, &blobs.Blob{ContentType: format.ContentType, ExpiresAt: expiresAt})
+	}
+	if err != nil {
+		utils.HandleResponseErr(w, h.logger, "can't store export: ", err)
+		return
+	}
+	url, err := h.blobs.SignedURL(ctx, key, h.exportLinkTTL)
+	if err != nil {
+		utils.HandleResponseErr(w, h.logger, "can't sign export URL: ", err)
+		return
+	}
+
+	utils.RenderJson(w, domain.ExportLinkResponse{URL: url, ExpiresAt: expiresAt.UTC(), Rows: rows, Truncated: truncated})
+}
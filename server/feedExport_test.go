@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	feed "github.com/nikita-itmo-gh-acc/car_estimator_api_contracts/gen/feed_v1"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/blobs"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/domain"
+	"google.golang.org/grpc"
+)
+
+// pagedFeed lists its listings in backend pages.
+type pagedFeed struct {
+	feed.FeedServiceClient
+	listings []*feed.CarListing
+}
+
+func (f *pagedFeed) ListListings(_ context.Context, in *feed.ListListingsRequest, _ ...grpc.CallOption) (*feed.ListListingsResponse, error) {
+	size := int(in.Page.PageSize)
+	from := min((int(in.Page.PageNumber)-1)*size, len(f.listings))
+	to := min(from+size, len(f.listings))
+	return &feed.ListListingsResponse{
+		Listings: f.listings[from:to],
+		PageMetadata: &feed.PageResponseMetadata{
+			TotalItems: int32(len(f.listings)),
+			TotalPages: int32((len(f.listings) + size - 1) / size),
+		},
+	}, nil
+}
+
+func TestExportRowCap(t *testing.T) {
+	tests := []struct {
+		name          string
+		listings      int
+		exportRows    int
+		wantRows      int
+		wantTruncated bool
+	}{
+		{"under the cap", 3, 5, 3, false},
+		{"exactly the cap", 5, 5, 5, false},
+		{"over the cap", 8, 5, 5, true},
+		{"over the cap across pages", 250, 120, 120, true},
+	}
+	for _, tt := range tests {
+		for _, delivery := range []string{"stream", "link"} {
+			t.Run(tt.name+"/"+delivery, func(t *testing.T) {
+				client := &pagedFeed{}
+				for i := range tt.listings {
+					client.listings = append(client.listings, &feed.CarListing{ListingId: fmt.Sprint("l", i), Price: 1000})
+				}
+				blobStore := blobs.NewMemory(blobs.NewSigner("secret", "/blobs"), 1<<20)
+				h := &FeedHandler{
+					client:        client,
+					logger:        slog.New(slog.NewTextHandler(io.Discard, nil)),
+					scanLimit:     1000,
+					exportRows:    tt.exportRows,
+					blobs:         blobStore,
+					exportLinkTTL: time.Hour,
+				}
+
+				req := httptest.NewRequest("GET", "/feed/listings/export?format=ndjson&delivery="+delivery, nil)
+				rec := httptest.NewRecorder()
+				h.ExportListings(rec, req)
+				resp := rec.Result()
+				if resp.StatusCode != 200 {
+					t.Fatalf("status = %d: %s", resp.StatusCode, rec.Body)
+				}
+
+				var rows int
+				var truncated bool
+				if delivery == "link" {
+					var link domain.ExportLinkResponse
+					if err := json.NewDecoder(resp.Body).Decode(&link); err != nil {
+						t.Fatal(err)
+					}
+					rows, truncated = link.Rows, link.Truncated
+				} else {
+					body, _ := io.ReadAll(resp.Body)
+					rows = strings.Count(string(body), "\n")
+					if resp.Header.Get("Trailer") != exportTruncatedTrailer {
+						t.Errorf("Trailer = %q, want %s declared", resp.Header.Get("Trailer"), exportTruncatedTrailer)
+					}
+					truncated = resp.Trailer.Get(exportTruncatedTrailer) == "true"
+				}
+				if rows != tt.wantRows || truncated != tt.wantTruncated {
+					t.Errorf("exported %d rows, truncated %v, want %d rows, truncated %v", rows, truncated, tt.wantRows, tt.wantTruncated)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/domain"
+)
+
+// facetCounter aggregates listings into term buckets (make, body_type...)
+// and range buckets (price, year).
+type facetCounter struct {
+	req    *domain.FacetRequest
+	terms  map[string]map[string]int
+	ranges map[string]map[float64]int
+}
+
+func newFacetCounter(req *domain.FacetRequest) *facetCounter {
+	return &facetCounter{
+		req:    req,
+		terms:  map[string]map[string]int{},
+		ranges: map[string]map[float64]int{},
+	}
+}
+
+func termValue(name string, l *domain.CarListing) string {
+	switch name {
+	case "make":
+		return l.Make
+	case "model_name":
+		return l.ModelName
+	case "body_type":
+		return l.BodyType
+	case "transmission":
+		return l.Transmission
+	case "drivetrain":
+		return l.Drivetrain
+	case "engine_type":
+		return l.EngineType
+	case "color":
+		return l.Color
+	case "condition":
+		return l.Condition
+	case "deal_type":
+		return l.DealType
+	}
+	return ""
+}
+
+func (c *facetCounter) Add(l *domain.CarListing) {
+	for _, name := range c.req.Names {
+		switch name {
+		case "price":
+			c.addRange(name, l.Price, c.req.PriceBucket)
+		case "year":
+			c.addRange(name, float64(l.Year), float64(c.req.YearBucket))
+		default:
+			if c.terms[name] == nil {
+				c.terms[name] = map[string]int{}
+			}
+			c.terms[name][termValue(name, l)]++
+		}
+	}
+}
+
+func (c *facetCounter) addRange(name string, value, size float64) {
+	if c.ranges[name] == nil {
+		c.ranges[name] = map[float64]int{}
+	}
+	c.ranges[name][math.Floor(value/size)*size]++
+}
+
+func (c *facetCounter) Result() map[string][]domain.FacetBucket {
+	out := map[string][]domain.FacetBucket{}
+
+	for _, name := range c.req.Names {
+		buckets := []domain.FacetBucket{}
+
+		switch name {
+		case "price", "year":
+			size := c.req.PriceBucket
+			if name == "year" {
+				size = float64(c.req.YearBucket)
+			}
+			for from, count := range c.ranges[name] {
+				to := from + size
+				buckets = append(buckets, domain.FacetBucket{
+					Value: fmt.Sprintf("%g-%g", from, to),
+					From:  &from,
+					To:    &to,
+					Count: count,
+				})
+			}
+			slices.SortFunc(buckets, func(a, b domain.FacetBucket) int {
+				return cmp.Compare(*a.From, *b.From)
+			})
+		default:
+			for value, count := range c.terms[name] {
+				buckets = append(buckets, domain.FacetBucket{Value: value, Count: count})
+			}
+			slices.SortFunc(buckets, func(a, b domain.FacetBucket) int {
+				return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Value, b.Value))
+			})
+		}
+
+		out[name] = buckets
+	}
+
+	return out
+}
@@ -0,0 +1,168 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/gorilla/mux"
+	feed "github.com/nikita-itmo-gh-acc/car_estimator_api_contracts/gen/feed_v1"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/domain"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/mappers"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/utils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// currentUser is the user of request access token, empty if there is no
+// valid one.
+func (h *FeedHandler) currentUser(r *http.Request) string {
+	userId, _ := h.tokens.UserId(utils.GetAccessToken(r))
+	return userId
+}
+
+// authorizeUser checks that request access token belongs to {userId} of the
+// route. On failure it writes 401 or 403 and returns false. Without
+// ACCESS_TOKEN_SECRET tokens can't be verified, so {userId} is trusted as it
+// was before the gateway checked them.
+func (h *FeedHandler) authorizeUser(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if h.tokens == nil {
+		return mux.Vars(r)["userId"], true
+	}
+	userId := h.currentUser(r)
+	switch {
+	case userId == "":
+		http.Error(w, "valid access token is required", http.StatusUnauthorized)
+		return "", false
+	case userId != mux.Vars(r)["userId"]:
+		http.Error(w, "access token belongs to another user", http.StatusForbidden)
+		return "", false
+	}
+	return userId, true
+}
+
+// markFavorites sets is_favorite of listings for the authenticated user,
+// leaving it out for anonymous requests.
+func (h *FeedHandler) markFavorites(r *http.Request, listings []domain.CarListing) {
+	userId := h.currentUser(r)
+	if userId == "" || len(listings) == 0 {
+		return
+	}
+
+	ids := make([]string, len(listings))
+	for i := range listings {
+		ids[i] = listings[i].ListingId
+	}
+	found, err := h.favorites.Contains(r.Context(), userId, ids)
+	if err != nil {
+		h.logger.Warn("can't read favorites", slog.String("user", userId), slog.Any("error", err))
+		return
+	}
+	for i := range listings {
+		isFavorite := found[listings[i].ListingId]
+		listings[i].IsFavorite = &isFavorite
+	}
+}
+
+// ListFavorites pages through favorites kept on the gateway, newest first,
+// loading the listings from feed service. Listings deleted since are dropped
+// from favorites, so such page can be shorter. fields= projects listings as
+// on the other read routes.
+func (h *FeedHandler) ListFavorites(w http.ResponseWriter, r *http.Request) {
+	log := h.logger.With(slog.String("op", "ListFavorites"))
+
+	userId, ok := h.authorizeUser(w, r)
+	if !ok {
+		return
+	}
+	fields, ok := h.fieldSet(w, r, "favorites")
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	pageNum, _ := strconv.Atoi(q.Get("page_number"))
+	if pageNum < 1 {
+		pageNum = 1
+	}
+	pageSize, _ := strconv.Atoi(q.Get("page_size"))
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	pageSize = min(pageSize, scanPageSize)
+
+	favorites, err := h.favorites.List(r.Context(), userId)
+	if err != nil {
+		utils.HandleResponseErr(w, h.logger, "ListFavorites failed: ", err)
+		return
+	}
+	from := min((pageNum-1)*pageSize, len(favorites))
+	page := favorites[from:min(from+pageSize, len(favorites))]
+
+	log.Info("→ gRPC GetListing for favorites", slog.String("user", userId), slog.Int("count", len(page)))
+	var (
+		wg       sync.WaitGroup
+		listings = make([]*feed.CarListing, len(page))
+		errs     = make([]error, len(page))
+	)
+	for i, f := range page {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := h.client.GetListing(r.Context(), &feed.GetListingRequest{ListingId: f.ListingId})
+			listings[i], errs[i] = resp.GetListing(), err
+		}()
+	}
+	wg.Wait()
+
+	out := domain.ListFavoritesResponse{
+		Listings: []domain.CarListing{},
+		PageMetadata: domain.PageResponseMetadata{
+			TotalItems:  int32(len(favorites)),
+			TotalPages:  int32((len(favorites) + pageSize - 1) / pageSize),
+			CurrentPage: int32(pageNum),
+		},
+	}
+	isFavorite := true
+	for i, err := range errs {
+		if status.Code(err) == codes.NotFound {
+			log.Info("dropping deleted listing from favorites", slog.String("user", userId), slog.String("id", page[i].ListingId))
+			h.favorites.Remove(r.Context(), userId, page[i].ListingId)
+			continue
+		}
+		if err != nil {
+			utils.HandleResponseErr(w, h.logger, "ListFavorites failed: ", err)
+			return
+		}
+		l := mappers.ToDomain(listings[i])
+		l.IsFavorite = &isFavorite
+		out.Listings = append(out.Listings, *l)
+	}
+
+	renderListings(w, out, fields)
+}
+
+// RemoveFromFavorites deletes a favorite kept on the gateway, as feed service
+// has no RPC for it.
+func (h *FeedHandler) RemoveFromFavorites(w http.ResponseWriter, r *http.Request) {
+	log := h.logger.With(slog.String("op", "RemoveFromFavorites"))
+
+	userId, ok := h.authorizeUser(w, r)
+	if !ok {
+		return
+	}
+	listingId := mux.Vars(r)["listingId"]
+	log.Info("removing favorite", slog.String("user", userId), slog.String("id", listingId))
+
+	removed, err := h.favorites.Remove(r.Context(), userId, listingId)
+	if err != nil {
+		utils.HandleResponseErr(w, h.logger, "RemoveFromFavorites failed: ", err)
+		return
+	}
+	if !removed {
+		http.Error(w, "listing isn't in user favorites", http.StatusNotFound)
+		return
+	}
+
+	utils.RenderJson(w, domain.RemoveFromFavoritesResponse{Success: true})
+}
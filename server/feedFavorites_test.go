@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	feed "github.com/nikita-itmo-gh-acc/car_estimator_api_contracts/gen/feed_v1"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/config"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/store"
+	"google.golang.org/grpc"
+)
+
+// favoritesFeed accepts any favorite and has every listing.
+type favoritesFeed struct {
+	feed.FeedServiceClient
+}
+
+func (favoritesFeed) GetListing(_ context.Context, in *feed.GetListingRequest, _ ...grpc.CallOption) (*feed.GetListingResponse, error) {
+	return &feed.GetListingResponse{Listing: &feed.CarListing{ListingId: in.ListingId, Price: 1000, Make: "Lada"}}, nil
+}
+
+func (favoritesFeed) AddToFavorites(context.Context, *feed.AddToFavoritesRequest, ...grpc.CallOption) (*feed.AddToFavoritesResponse, error) {
+	return &feed.AddToFavoritesResponse{Success: true}, nil
+}
+
+func TestAddToFavorites(t *testing.T) {
+	tests := []struct {
+		name       string
+		secret     string
+		wantStatus int
+	}{
+		{"default config trusts userId", "", http.StatusOK},
+		{"secret requires a token", "secret", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := config.Default()
+			conf.AccessTokenSecret = tt.secret
+			conf.ProfileServiceAddr, conf.PredictionServiceAddr, conf.FeedServiceAddr = "localhost:0", "localhost:0", "localhost:0"
+			s, err := NewServer(conf, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			h := s.handlers["feed"].(*FeedHandler)
+			h.client = favoritesFeed{}
+			h.setupRoutes()
+
+			req := httptest.NewRequest("POST", "/feed/users/u1/favorites", strings.NewReader(`{"listing_id":"l1"}`))
+			rec := httptest.NewRecorder()
+			s.r.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+
+			found, _ := h.favorites.Contains(context.Background(), "u1", []string{"l1"})
+			if found["l1"] != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("favorite kept = %v after status %d", found["l1"], rec.Code)
+			}
+		})
+	}
+}
+
+func TestListFavoritesFields(t *testing.T) {
+	favorites, _ := store.NewFavorites("")
+	favorites.Add(context.Background(), "u1", "l1")
+	r := mux.NewRouter()
+	h := &FeedHandler{
+		r:         r.PathPrefix("/feed").Subrouter(),
+		client:    favoritesFeed{},
+		logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+		favorites: favorites,
+		fieldPresets: map[string]map[string][]string{
+			"favorites": {"card": {"listing_id", "price"}},
+		},
+	}
+	h.setupRoutes()
+
+	for _, fields := range []string{"listing_id,price", "card"} {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", "/feed/users/u1/favorites?fields="+fields, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("fields=%s: status = %d: %s", fields, rec.Code, rec.Body)
+		}
+		var out struct {
+			Listings []map[string]any `json:"listings"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &out)
+		if len(out.Listings) != 1 || len(out.Listings[0]) != 2 || out.Listings[0]["listing_id"] != "l1" || out.Listings[0]["price"] != 1000.0 {
+			t.Errorf("fields=%s: listings = %v, want l1 with listing_id and price only", fields, out.Listings)
+		}
+	}
+}
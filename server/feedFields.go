@@ -0,0 +1,85 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"slices"
+
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/mappers"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/utils"
+)
+
+// fieldSet reads fields= param using presets of the route, writing 400 on
+// unknown names.
+func (h *FeedHandler) fieldSet(w http.ResponseWriter, r *http.Request, route string) ([]string, bool) {
+	presets := maps.Clone(h.fieldPresets[""])
+	if presets == nil {
+		presets = map[string][]string{}
+	}
+	maps.Copy(presets, h.fieldPresets[route])
+
+	fields, err := mappers.ToFieldSet(r.URL.Query(), presets)
+	if err != nil {
+		http.Error(w, "bad fields: "+err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	// with_valuation=true implies the valuation field
+	if fields != nil && r.URL.Query().Get("with_valuation") == "true" && !slices.Contains(fields, "valuation") {
+		fields = append(fields, "valuation")
+	}
+	return fields, true
+}
+
+// renderListings writes out with "listing" and "listings" members cut down
+// to fields, keeping the order of CarListing.
+func renderListings(w http.ResponseWriter, out any, fields []string) {
+	if fields == nil {
+		utils.RenderJson(w, out)
+		return
+	}
+
+	raw, _ := json.Marshal(out)
+	var doc map[string]json.RawMessage
+	json.Unmarshal(raw, &doc)
+
+	if v, ok := doc["listing"]; ok {
+		doc["listing"] = projectListing(v, fields)
+	}
+	if v, ok := doc["listings"]; ok {
+		var listings []json.RawMessage
+		json.Unmarshal(v, &listings)
+		for i := range listings {
+			listings[i] = projectListing(listings[i], fields)
+		}
+		doc["listings"], _ = json.Marshal(listings)
+	}
+
+	utils.RenderJson(w, doc)
+}
+
+func projectListing(raw json.RawMessage, fields []string) json.RawMessage {
+	var all map[string]json.RawMessage
+	if json.Unmarshal(raw, &all) != nil || all == nil {
+		return raw
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for _, f := range fields {
+		v, ok := all[f]
+		if !ok {
+			continue
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(f)
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes()
+}
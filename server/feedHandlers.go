@@ -1,17 +1,23 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
-	"strconv"
-	"strings"
+	"time"
 
 	"log/slog"
 
 	"github.com/gorilla/mux"
 	feed "github.com/nikita-itmo-gh-acc/car_estimator_api_contracts/gen/feed_v1"
+	model "github.com/nikita-itmo-gh-acc/car_estimator_api_contracts/gen/prediction_v1/go"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/auth"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/blobs"
 	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/domain"
 	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/mappers"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/notify"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/pagination"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/store"
 	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/utils"
 	"google.golang.org/grpc"
 )
@@ -20,118 +26,111 @@ type FeedHandler struct {
 	r      *mux.Router
 	logger *slog.Logger
 	client feed.FeedServiceClient
+	// predictions value listings, the connection is prediction backend one
+	predictions model.PredictionServiceClient
+	scanLimit   int
+	cursors     *pagination.Codec
+
+	fieldPresets map[string]map[string][]string
+	bulk         *bulkImporter
+	exportRows   int
+
+	favorites store.Favorites
+	tokens    *auth.Verifier
+	searches  store.SavedSearches
+	inbox     store.Inbox
+	notifier  notify.Sink
+	watches   store.Watches
+	events    *notify.Stream
+	changes   *listingEvents
+	valuation domain.ValuationThresholds
+
+	valuationConcurrency int
+	valuationBudget      time.Duration
+
+	blobs         blobs.Store
+	exportLinkTTL time.Duration
 }
 
-func (h *FeedHandler) setupgRPC(conn *grpc.ClientConn) {
+func (h *FeedHandler) setupgRPC(conn grpc.ClientConnInterface) {
 	h.client = feed.NewFeedServiceClient(conn)
 }
 
 func (h *FeedHandler) setupRoutes() {
 	h.r.HandleFunc("/listings", h.ListListings).Methods("GET")
 	h.r.HandleFunc("/listings/search", h.SearchListings).Methods("GET")
+	h.r.HandleFunc("/listings/export", h.ExportListings).Methods("GET")
+	h.r.HandleFunc("/listings/stream", h.StreamListings).Methods("GET")
+	h.r.HandleFunc("/listings/bulk", h.ImportListings).Methods("POST")
+	h.r.HandleFunc("/listings/bulk/{jobId}", h.GetImportJob).Methods("GET")
 	h.r.HandleFunc("/listings/{listingId}", h.GetListing).Methods("GET")
 	h.r.HandleFunc("/listings", h.CreateListing).Methods("POST")
 	h.r.HandleFunc("/listings/{listingId}", h.UpdateListing).Methods("PUT")
+	h.r.HandleFunc("/listings/{listingId}", h.PatchListing).Methods("PATCH")
 	h.r.HandleFunc("/listings/{listingId}", h.DeleteListing).Methods("DELETE")
+	h.r.HandleFunc("/listings/{listingId}/valuation", h.GetListingValuation).Methods("GET")
+	h.r.HandleFunc("/listings/{listingId}/watch", h.WatchListing).Methods("POST")
+	h.r.HandleFunc("/listings/{listingId}/watch", h.UnwatchListing).Methods("DELETE")
+	h.r.HandleFunc("/users/{userId}/favorites", h.ListFavorites).Methods("GET")
 	h.r.HandleFunc("/users/{userId}/favorites", h.AddToFavorites).Methods("POST")
+	h.r.HandleFunc("/users/{userId}/favorites/{listingId}", h.RemoveFromFavorites).Methods("DELETE")
+	h.r.HandleFunc("/users/{userId}/searches", h.ListSavedSearches).Methods("GET")
+	h.r.HandleFunc("/users/{userId}/searches", h.CreateSavedSearch).Methods("POST")
+	h.r.HandleFunc("/users/{userId}/searches/{searchId}", h.GetSavedSearch).Methods("GET")
+	h.r.HandleFunc("/users/{userId}/searches/{searchId}", h.UpdateSavedSearch).Methods("PUT")
+	h.r.HandleFunc("/users/{userId}/searches/{searchId}", h.DeleteSavedSearch).Methods("DELETE")
+	h.r.HandleFunc("/users/{userId}/notifications", h.ListNotifications).Methods("GET")
+	h.r.HandleFunc("/users/{userId}/notifications/stream", h.StreamNotifications).Methods("GET")
+	h.r.HandleFunc("/users/{userId}/notifications/{notificationId}", h.DeleteNotification).Methods("DELETE")
 }
 
-func (h *FeedHandler) ListListings(w http.ResponseWriter, r *http.Request) {
-	log := h.logger.With(slog.String("op", "ListListings"))
+func (h *FeedHandler) listPages(ctx context.Context, page *feed.PageRequest, sortBy feed.SortBy) ([]*feed.CarListing, *feed.PageResponseMetadata, error) {
+	resp, err := h.client.ListListings(ctx, &feed.ListListingsRequest{Page: page, SortBy: sortBy})
+	return resp.GetListings(), resp.GetPageMetadata(), err
+}
 
-	q := r.URL.Query()
-	pageNum, _ := strconv.Atoi(q.Get("page_number"))
-	if pageNum < 1 {
-		pageNum = 1
-	}
-	pageSize, _ := strconv.Atoi(q.Get("page_size"))
-	if pageSize < 1 {
-		pageSize = 10
-	}
-	sortByStr := strings.ToUpper(q.Get("sort_by"))
-	sortBy := feed.SortBy_SORT_UNSPECIFIED
-	if v, ok := feed.SortBy_value[sortByStr]; ok {
-		sortBy = feed.SortBy(v)
+func (h *FeedHandler) searchPages(query string) pageFetcher {
+	return func(ctx context.Context, page *feed.PageRequest, sortBy feed.SortBy) ([]*feed.CarListing, *feed.PageResponseMetadata, error) {
+		resp, err := h.client.SearchListings(ctx, &feed.SearchListingsRequest{Query: query, Page: page, SortBy: sortBy})
+		return resp.GetListings(), resp.GetPageMetadata(), err
 	}
+}
 
-	grpcReq := &feed.ListListingsRequest{
-		Page: &feed.PageRequest{
-			PageNumber: int32(pageNum),
-			PageSize:   int32(pageSize),
-		},
-		SortBy: sortBy,
+func (h *FeedHandler) ListListings(w http.ResponseWriter, r *http.Request) {
+	fields, ok := h.fieldSet(w, r, "list")
+	if !ok {
+		return
 	}
 
-	log.Info("→ gRPC ListListings", slog.Any("req", grpcReq))
-	grpcResp, err := h.client.ListListings(r.Context(), grpcReq)
-	if err != nil {
-		utils.HandleResponseErr(w, h.logger, "ListListings failed: ", err)
+	page, ok := h.queryListings(w, r, "ListListings", h.listPages, false)
+	if !ok {
 		return
 	}
 
-	out := domain.ListListingsResponse{
-		PageMetadata: domain.PageResponseMetadata{
-			TotalItems:  grpcResp.PageMetadata.TotalItems,
-			TotalPages:  grpcResp.PageMetadata.TotalPages,
-			CurrentPage: grpcResp.PageMetadata.CurrentPage,
-		},
-		Listings: make([]domain.CarListing, len(grpcResp.Listings)),
+	if r.URL.Query().Get("with_valuation") == "true" {
+		page.meta.ValuationMissing = h.enrichValuations(r.Context(), page.listings)
 	}
-	for i, c := range grpcResp.Listings {
-		out.Listings[i] = *mappers.ToDomain(c)
-	}
-
-	utils.RenderJson(w, out)
+	h.markFavorites(r, page.listings)
+	renderListings(w, domain.ListListingsResponse{Listings: page.listings, PageMetadata: page.meta}, fields)
 }
 
 func (h *FeedHandler) SearchListings(w http.ResponseWriter, r *http.Request) {
-	log := h.logger.With(slog.String("op", "SearchListings"))
-
-	q := r.URL.Query()
-	query := q.Get("query")
-	pageNum, _ := strconv.Atoi(q.Get("page_number"))
-	if pageNum < 1 {
-		pageNum = 1
-	}
-	pageSize, _ := strconv.Atoi(q.Get("page_size"))
-	if pageSize < 1 {
-		pageSize = 10
-	}
-	sortByStr := strings.ToUpper(q.Get("sort_by"))
-	sortBy := feed.SortBy_SORT_UNSPECIFIED
-	if v, ok := feed.SortBy_value[sortByStr]; ok {
-		sortBy = feed.SortBy(v)
-	}
-
-	grpcReq := &feed.SearchListingsRequest{
-		Query: query,
-		Page: &feed.PageRequest{
-			PageNumber: int32(pageNum),
-			PageSize:   int32(pageSize),
-		},
-		SortBy: sortBy,
+	query := r.URL.Query().Get("query")
+	fields, ok := h.fieldSet(w, r, "search")
+	if !ok {
+		return
 	}
 
-	log.Info("→ gRPC SearchListings", slog.Any("req", grpcReq))
-	grpcResp, err := h.client.SearchListings(r.Context(), grpcReq)
-	if err != nil {
-		utils.HandleResponseErr(w, h.logger, "SearchListings failed: ", err)
+	page, ok := h.queryListings(w, r, "SearchListings", h.searchPages(query), true)
+	if !ok {
 		return
 	}
 
-	out := domain.SearchListingsResponse{
-		PageMetadata: domain.PageResponseMetadata{
-			TotalItems:  grpcResp.PageMetadata.TotalItems,
-			TotalPages:  grpcResp.PageMetadata.TotalPages,
-			CurrentPage: grpcResp.PageMetadata.CurrentPage,
-		},
-		Listings: make([]domain.CarListing, len(grpcResp.Listings)),
+	if r.URL.Query().Get("with_valuation") == "true" {
+		page.meta.ValuationMissing = h.enrichValuations(r.Context(), page.listings)
 	}
-	for i, c := range grpcResp.Listings {
-		out.Listings[i] = *mappers.ToDomain(c)
-	}
-
-	utils.RenderJson(w, out)
+	h.markFavorites(r, page.listings)
+	renderListings(w, domain.SearchListingsResponse{Listings: page.listings, PageMetadata: page.meta, Facets: page.facets}, fields)
 }
 
 func (h *FeedHandler) GetListing(w http.ResponseWriter, r *http.Request) {
@@ -139,6 +138,10 @@ func (h *FeedHandler) GetListing(w http.ResponseWriter, r *http.Request) {
 
 	id := mux.Vars(r)["listingId"]
 	grpcReq := &feed.GetListingRequest{ListingId: id}
+	fields, ok := h.fieldSet(w, r, "get")
+	if !ok {
+		return
+	}
 
 	log.Info("→ gRPC GetListing", slog.String("id", id))
 	grpcResp, err := h.client.GetListing(r.Context(), grpcReq)
@@ -147,11 +150,14 @@ func (h *FeedHandler) GetListing(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	listing := []domain.CarListing{*mappers.ToDomain(grpcResp.Listing)}
+	w.Header().Set("ETag", listingETag(&listing[0]))
+	h.markFavorites(r, listing)
+
 	out := domain.GetListingResponse{
-		Listing: *mappers.ToDomain(grpcResp.Listing),
+		Listing: listing[0],
 	}
-
-	utils.RenderJson(w, out)
+	renderListings(w, out, fields)
 }
 
 func (h *FeedHandler) CreateListing(w http.ResponseWriter, r *http.Request) {
@@ -177,6 +183,7 @@ func (h *FeedHandler) CreateListing(w http.ResponseWriter, r *http.Request) {
 	out := domain.CreateListingResponse{
 		Listing: *mappers.ToDomain(grpcResp.GetListing()),
 	}
+	h.changes.publish(domain.ListingCreated, out.Listing.ListingId, &out.Listing)
 	utils.RenderJson(w, out)
 }
 
@@ -191,9 +198,24 @@ func (h *FeedHandler) UpdateListing(w http.ResponseWriter, r *http.Request) {
 	}
 	body.Listing.ListingId = listingId
 
+	if r.Header.Get("If-Match") != "" {
+		current, err := h.client.GetListing(r.Context(), &feed.GetListingRequest{ListingId: listingId})
+		if err != nil {
+			utils.HandleResponseErr(w, h.logger, "UpdateListing failed: ", err)
+			return
+		}
+		if etag := listingETag(mappers.ToDomain(current.GetListing())); !ifMatch(r, etag) {
+			w.Header().Set("ETag", etag)
+			http.Error(w, "listing was changed, If-Match doesn't match "+etag, http.StatusPreconditionFailed)
+			return
+		}
+	}
+
 	grpcReq := &feed.UpdateListingRequest{
 		Listing: mappers.ToMessage(&body.Listing),
 	}
+	// ToMessage leaves out the id, it's set by backend on create
+	grpcReq.Listing.ListingId = listingId
 
 	log.Info("→ gRPC UpdateListing", slog.Any("req", grpcReq))
 	grpcResp, err := h.client.UpdateListing(r.Context(), grpcReq)
@@ -205,6 +227,8 @@ func (h *FeedHandler) UpdateListing(w http.ResponseWriter, r *http.Request) {
 	out := domain.UpdateListingResponse{
 		Listing: *mappers.ToDomain(grpcResp.GetListing()),
 	}
+	h.changes.publish(domain.ListingUpdated, listingId, &out.Listing)
+	w.Header().Set("ETag", listingETag(&out.Listing))
 	utils.RenderJson(w, out)
 }
 
@@ -222,6 +246,9 @@ func (h *FeedHandler) DeleteListing(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if grpcResp.Success {
+		h.changes.publish(domain.ListingDeleted, listingId, nil)
+	}
 	out := domain.DeleteListingResponse{Success: grpcResp.Success}
 	utils.RenderJson(w, out)
 }
@@ -229,7 +256,10 @@ func (h *FeedHandler) DeleteListing(w http.ResponseWriter, r *http.Request) {
 func (h *FeedHandler) AddToFavorites(w http.ResponseWriter, r *http.Request) {
 	log := h.logger.With(slog.String("op", "AddToFavorites"))
 
-	userId := mux.Vars(r)["userId"]
+	userId, ok := h.authorizeUser(w, r)
+	if !ok {
+		return
+	}
 	var body domain.AddToFavoritesRequest
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		http.Error(w, "bad JSON: "+err.Error(), http.StatusBadRequest)
@@ -247,6 +277,13 @@ func (h *FeedHandler) AddToFavorites(w http.ResponseWriter, r *http.Request) {
 		utils.HandleResponseErr(w, h.logger, "AddToFavorites failed: ", err)
 		return
 	}
+	// feed service can't list or remove favorites, so they are kept here too
+	if grpcResp.Success {
+		if err = h.favorites.Add(r.Context(), userId, body.ListingId); err != nil {
+			utils.HandleResponseErr(w, h.logger, "AddToFavorites failed: ", err)
+			return
+		}
+	}
 
 	out := domain.AddToFavoritesResponse{Success: grpcResp.Success}
 	utils.RenderJson(w, out)
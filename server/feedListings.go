@@ -0,0 +1,172 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	feed "github.com/nikita-itmo-gh-acc/car_estimator_api_contracts/gen/feed_v1"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/domain"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/mappers"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/pagination"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/utils"
+)
+
+// listingsPage is the outcome of a list or search query, shared by both endpoints.
+type listingsPage struct {
+	listings []domain.CarListing
+	meta     domain.PageResponseMetadata
+	facets   map[string][]domain.FacetBucket
+}
+
+// paginationParams are query params which don't change the result set.
+var paginationParams = []string{"cursor", "page_number", "page_size", "facets", "price_bucket", "year_bucket", "fields", "with_valuation"}
+
+// queryListings serves ListListings and SearchListings: page_number or cursor
+// pagination over fetch, gateway-side filters and facets. On failure it writes
+// the error response and returns false.
+func (h *FeedHandler) queryListings(w http.ResponseWriter, r *http.Request, op string, fetch pageFetcher, withFacets bool) (*listingsPage, bool) {
+	log := h.logger.With(slog.String("op", op))
+
+	q := r.URL.Query()
+	pageNum, _ := strconv.Atoi(q.Get("page_number"))
+	if pageNum < 1 {
+		pageNum = 1
+	}
+	pageSize, _ := strconv.Atoi(q.Get("page_size"))
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	sort, err := mappers.ToSort(q)
+	if err != nil {
+		http.Error(w, "bad sort: "+err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	order := newListingOrder(sort)
+
+	filter, err := mappers.ToListingFilter(q)
+	if err != nil {
+		http.Error(w, "bad filter: "+err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	var facets *domain.FacetRequest
+	if withFacets {
+		if facets, err = mappers.ToFacetRequest(q); err != nil {
+			http.Error(w, "bad facets: "+err.Error(), http.StatusBadRequest)
+			return nil, false
+		}
+	}
+
+	scopeQuery := r.URL.Query()
+	for _, p := range paginationParams {
+		scopeQuery.Del(p)
+	}
+	scope := pagination.Scope(r.URL.Path, scopeQuery.Encode())
+
+	var cur *pagination.Cursor
+	if token := q.Get("cursor"); token != "" {
+		if cur, err = h.cursors.Decode(token, scope); err != nil {
+			http.Error(w, "bad cursor: "+err.Error(), http.StatusBadRequest)
+			return nil, false
+		}
+	}
+
+	var (
+		page             = &listingsPage{}
+		items            []positioned
+		total            int32
+		hasNext, hasPrev bool
+		counter          *facetCounter
+		onMatch          func(l *domain.CarListing)
+	)
+	if facets != nil {
+		counter = newFacetCounter(facets)
+		onMatch = counter.Add
+	}
+
+	switch {
+	case cur != nil:
+		// page_number pages all come in backend order, only pages read from a
+		// cursor are ordered on the gateway
+		log.Info("→ gRPC "+op+" from cursor", slog.Any("cursor", cur), slog.Any("filter", filter))
+		res, err := h.walkFromCursor(r.Context(), fetch, order, cur, filter, pageSize)
+		if err != nil {
+			utils.HandleResponseErr(w, h.logger, op+" failed: ", err)
+			return nil, false
+		}
+
+		items, total = res.listings, res.total
+		page.meta = domain.PageResponseMetadata{Filters: filter, Truncated: res.truncated}
+		if filter == nil {
+			page.meta.TotalItems = res.total
+			page.meta.TotalPages = int32((int(res.total) + pageSize - 1) / pageSize)
+		}
+
+		hasNext, hasPrev = res.more || cur.Backward, res.more || !cur.Backward
+
+		if counter != nil {
+			if _, _, _, err = h.filterListings(r.Context(), fetch, order, filter, 1, pageSize, onMatch); err != nil {
+				utils.HandleResponseErr(w, h.logger, op+" failed: ", err)
+				return nil, false
+			}
+		}
+
+	case filter != nil || facets != nil || !order.direct():
+		log.Info("→ gRPC "+op+" with gateway-side filter, sort and facets", slog.Any("filter", filter), slog.String("sort", sort.String()), slog.Any("facets", facets))
+		items, page.meta, total, err = h.filterListings(r.Context(), fetch, order, filter, pageNum, pageSize, onMatch)
+		if err != nil {
+			utils.HandleResponseErr(w, h.logger, op+" failed: ", err)
+			return nil, false
+		}
+		hasNext, hasPrev = page.meta.CurrentPage < page.meta.TotalPages, pageNum > 1
+
+	default:
+		req := &feed.PageRequest{PageNumber: int32(pageNum), PageSize: int32(pageSize)}
+		log.Info("→ gRPC "+op, slog.Any("page", req), slog.String("sort_by", order.backend.String()))
+		listings, meta, err := fetch(r.Context(), req, order.backend)
+		if err != nil {
+			utils.HandleResponseErr(w, h.logger, op+" failed: ", err)
+			return nil, false
+		}
+
+		page.meta = domain.PageResponseMetadata{
+			TotalItems:  meta.GetTotalItems(),
+			TotalPages:  meta.GetTotalPages(),
+			CurrentPage: meta.GetCurrentPage(),
+		}
+		for i, c := range listings {
+			items = append(items, positioned{*mappers.ToDomain(c), int32((pageNum-1)*pageSize + i)})
+		}
+		total = meta.GetTotalItems()
+		hasNext, hasPrev = page.meta.CurrentPage < page.meta.TotalPages, pageNum > 1
+	}
+
+	page.meta.Sort = sort.String()
+	page.listings = make([]domain.CarListing, len(items))
+	for i, p := range items {
+		page.listings[i] = p.listing
+	}
+	if counter != nil {
+		page.facets = counter.Result()
+	}
+
+	var links [][2]string
+	if len(items) > 0 {
+		if hasNext {
+			last := items[len(items)-1]
+			page.meta.NextCursor = h.cursors.Encode(newCursor(&last.listing, last.offset, total, sort, false, scope))
+			links = append(links, [2]string{"next", pagination.PageURL(r, map[string]string{"cursor": page.meta.NextCursor, "page_number": ""})})
+		}
+		if hasPrev && order.sorted() {
+			first := items[0]
+			page.meta.PrevCursor = h.cursors.Encode(newCursor(&first.listing, first.offset, total, sort, true, scope))
+			links = append(links, [2]string{"prev", pagination.PageURL(r, map[string]string{"cursor": page.meta.PrevCursor, "page_number": ""})})
+		}
+	}
+	if len(links) > 0 {
+		w.Header().Set("Link", pagination.LinkHeader(links))
+	}
+
+	return page, true
+}
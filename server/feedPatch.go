@@ -0,0 +1,141 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/gorilla/mux"
+	feed "github.com/nikita-itmo-gh-acc/car_estimator_api_contracts/gen/feed_v1"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/domain"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/mappers"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/utils"
+)
+
+const (
+	mergePatchType = "application/merge-patch+json"
+	jsonPatchType  = "application/json-patch+json"
+	patchBodyLimit = 1 << 20
+)
+
+// listingETag fingerprints listing state for optimistic concurrency.
+func listingETag(l *domain.CarListing) string {
+	raw, _ := json.Marshal(l)
+	sum := sha256.Sum256(raw)
+	return `"` + hex.EncodeToString(sum[:12]) + `"`
+}
+
+// ifMatch checks If-Match header against etag, a missing header matches anything.
+func ifMatch(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return true
+	}
+	for _, tag := range strings.Split(header, ",") {
+		if tag = strings.TrimSpace(tag); tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// PatchListing applies RFC 7396 merge patch or RFC 6902 JSON Patch to the
+// listing. Feed service only replaces whole listings, so the gateway reads the
+// current one, patches it and writes it back. If-Match is checked against the
+// state read, which narrows but doesn't close the window for lost updates.
+func (h *FeedHandler) PatchListing(w http.ResponseWriter, r *http.Request) {
+	log := h.logger.With(slog.String("op", "PatchListing"))
+
+	listingId := mux.Vars(r)["listingId"]
+	patch, err := io.ReadAll(http.MaxBytesReader(w, r.Body, patchBodyLimit))
+	var maxBytes *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytes):
+		http.Error(w, fmt.Sprintf("patch is too large, at most %d bytes are allowed", maxBytes.Limit), http.StatusRequestEntityTooLarge)
+		return
+	case err != nil:
+		http.Error(w, "can't read body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var apply func(doc []byte) ([]byte, error)
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case mergePatchType, "application/json":
+		if !json.Valid(patch) {
+			http.Error(w, "bad JSON merge patch", http.StatusBadRequest)
+			return
+		}
+		apply = func(doc []byte) ([]byte, error) {
+			return jsonpatch.MergePatch(doc, patch)
+		}
+	case jsonPatchType:
+		ops, err := jsonpatch.DecodePatch(patch)
+		if err != nil {
+			http.Error(w, "bad JSON Patch: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		apply = ops.Apply
+	default:
+		w.Header().Set("Accept-Patch", mergePatchType+", "+jsonPatchType)
+		http.Error(w, "unsupported patch format "+mediaType, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	log.Info("→ gRPC GetListing", slog.String("id", listingId))
+	current, err := h.client.GetListing(r.Context(), &feed.GetListingRequest{ListingId: listingId})
+	if err != nil {
+		utils.HandleResponseErr(w, h.logger, "PatchListing failed: ", err)
+		return
+	}
+
+	listing := mappers.ToDomain(current.GetListing())
+	if etag := listingETag(listing); !ifMatch(r, etag) {
+		w.Header().Set("ETag", etag)
+		http.Error(w, "listing was changed, If-Match doesn't match "+etag, http.StatusPreconditionFailed)
+		return
+	}
+
+	doc, _ := json.Marshal(listing)
+	if doc, err = apply(doc); err != nil {
+		http.Error(w, "can't apply patch: "+err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	var patched domain.CarListing
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.DisallowUnknownFields()
+	if err = dec.Decode(&patched); err != nil {
+		http.Error(w, "patched listing is invalid: "+err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if patched.ListingId != listingId {
+		http.Error(w, "listing_id can't be changed", http.StatusUnprocessableEntity)
+		return
+	}
+
+	grpcReq := &feed.UpdateListingRequest{Listing: mappers.ToMessage(&patched)}
+	grpcReq.Listing.ListingId = listingId
+	log.Info("→ gRPC UpdateListing", slog.Any("req", grpcReq))
+	grpcResp, err := h.client.UpdateListing(r.Context(), grpcReq)
+	if err != nil {
+		utils.HandleResponseErr(w, h.logger, "PatchListing failed: ", err)
+		return
+	}
+
+	out := domain.UpdateListingResponse{
+		Listing: *mappers.ToDomain(grpcResp.GetListing()),
+	}
+	h.changes.publish(domain.ListingUpdated, listingId, &out.Listing)
+	w.Header().Set("ETag", listingETag(&out.Listing))
+	utils.RenderJson(w, out)
+}
@@ -0,0 +1,24 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPatchListingBodyLimit(t *testing.T) {
+	h := &FeedHandler{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+	body := append([]byte(`{"description":"`), bytes.Repeat([]byte("a"), patchBodyLimit)...)
+	req := httptest.NewRequest(http.MethodPatch, "/feed/listings/l1", bytes.NewReader(append(body, `"}`...)))
+	req.Header.Set("Content-Type", mergePatchType)
+	rec := httptest.NewRecorder()
+	h.PatchListing(rec, req)
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusRequestEntityTooLarge)
+	}
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"slices"
+
+	feed "github.com/nikita-itmo-gh-acc/car_estimator_api_contracts/gen/feed_v1"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/domain"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/mappers"
+)
+
+const scanPageSize = 100
+
+// pageFetcher loads one backend page of ListListings or SearchListings.
+type pageFetcher func(ctx context.Context, page *feed.PageRequest, sortBy feed.SortBy) ([]*feed.CarListing, *feed.PageResponseMetadata, error)
+
+// positioned is a listing with its offset in backend order.
+type positioned struct {
+	listing domain.CarListing
+	offset  int32
+}
+
+// stream walks backend pages starting from fromPage until fn returns false,
+// the last page is reached or limit listings are seen. Runs of listings
+// backend sees as equal are buffered and sorted in order, since backend
+// order of them isn't stable between requests and directions. It reports
+// whether limit was hit and backend total of items.
+func stream(ctx context.Context, fetch pageFetcher, order listingOrder, fromPage int32, limit int, fn func(p positioned) bool) (bool, int32, error) {
+	var group []positioned
+	flush := func() bool {
+		slices.SortFunc(group, func(a, b positioned) int {
+			return order.compare(&a.listing, &b.listing)
+		})
+		for _, p := range group {
+			if !fn(p) {
+				return false
+			}
+		}
+		group = group[:0]
+		return true
+	}
+
+	seen := 0
+	for pageNum := fromPage; ; pageNum++ {
+		listings, meta, err := fetch(ctx, &feed.PageRequest{PageNumber: pageNum, PageSize: scanPageSize}, order.backend)
+		if err != nil {
+			return false, 0, err
+		}
+		total := meta.GetTotalItems()
+
+		for i, c := range listings {
+			if seen >= limit {
+				flush()
+				return true, total, nil
+			}
+			seen++
+
+			p := positioned{*mappers.ToDomain(c), (pageNum-1)*scanPageSize + int32(i)}
+			if !order.sorted() {
+				if !fn(p) {
+					return false, total, nil
+				}
+				continue
+			}
+			if len(group) > 0 && order.compareGroups(&group[0].listing, &p.listing) != 0 && !flush() {
+				return false, total, nil
+			}
+			group = append(group, p)
+		}
+
+		if len(listings) == 0 || pageNum >= meta.GetTotalPages() {
+			flush()
+			return false, total, nil
+		}
+	}
+}
+
+// filterListings pages through backend results applying the filter and sort
+// keys the feed service doesn't support, then cuts requested page out of
+// matched listings.
+// onMatch, if set, sees every matched listing, e.g. to aggregate facets.
+func (h *FeedHandler) filterListings(ctx context.Context, fetch pageFetcher, order listingOrder, filter *domain.ListingFilter, pageNum, pageSize int, onMatch func(l *domain.CarListing)) ([]positioned, domain.PageResponseMetadata, int32, error) {
+	var matched []positioned
+
+	truncated, total, err := stream(ctx, fetch, order, 1, h.scanLimit, func(p positioned) bool {
+		if filter.Match(&p.listing) {
+			matched = append(matched, p)
+			if onMatch != nil {
+				onMatch(&p.listing)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, domain.PageResponseMetadata{}, 0, err
+	}
+
+	meta := domain.PageResponseMetadata{
+		TotalItems:  int32(len(matched)),
+		TotalPages:  int32((len(matched) + pageSize - 1) / pageSize),
+		CurrentPage: int32(pageNum),
+		Filters:     filter,
+		Truncated:   truncated,
+	}
+
+	from := min((pageNum-1)*pageSize, len(matched))
+	to := min(from+pageSize, len(matched))
+	return matched[from:to], meta, total, nil
+}
@@ -0,0 +1,298 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/domain"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/mappers"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/store"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/utils"
+)
+
+const (
+	// notifyListings caps listings sent in one new-match notification.
+	notifyListings   = 20
+	searchRunTimeout = time.Minute
+)
+
+// decodeSavedSearch reads and checks saved search body, writing 400 on failure.
+func decodeSavedSearch(w http.ResponseWriter, r *http.Request) (*domain.SaveSearchRequest, bool) {
+	var body domain.SaveSearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "bad JSON: "+err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	sort, err := mappers.ToSort(url.Values{"sort": {body.Sort}})
+	if err != nil {
+		http.Error(w, "bad sort: "+err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	body.Sort = sort.String()
+
+	if err = body.Filter.Validate(); err != nil {
+		http.Error(w, "bad filter: "+err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	if body.Filter.IsEmpty() {
+		body.Filter = nil
+	}
+
+	if body.Name == "" {
+		body.Name = body.Query
+	}
+	return &body, true
+}
+
+func (h *FeedHandler) ListSavedSearches(w http.ResponseWriter, r *http.Request) {
+	userId, ok := h.authorizeUser(w, r)
+	if !ok {
+		return
+	}
+	searches, err := h.searches.List(r.Context(), userId)
+	if err != nil {
+		utils.HandleResponseErr(w, h.logger, "ListSavedSearches failed: ", err)
+		return
+	}
+	utils.RenderJson(w, domain.ListSavedSearchesResponse{Searches: searches})
+}
+
+func (h *FeedHandler) CreateSavedSearch(w http.ResponseWriter, r *http.Request) {
+	log := h.logger.With(slog.String("op", "CreateSavedSearch"))
+
+	userId, ok := h.authorizeUser(w, r)
+	if !ok {
+		return
+	}
+	body, ok := decodeSavedSearch(w, r)
+	if !ok {
+		return
+	}
+
+	search := domain.SavedSearch{
+		SearchId:  uuid.NewString(),
+		UserId:    userId,
+		Name:      body.Name,
+		Query:     body.Query,
+		Filter:    body.Filter,
+		Sort:      body.Sort,
+		CreatedAt: time.Now().UTC(),
+	}
+	log.Info("saving search", slog.Any("search", search))
+
+	if err := h.searches.Save(r.Context(), &search); err != nil {
+		utils.HandleResponseErr(w, h.logger, "CreateSavedSearch failed: ", err)
+		return
+	}
+	utils.RenderJson(w, domain.SavedSearchResponse{Search: search})
+}
+
+func (h *FeedHandler) GetSavedSearch(w http.ResponseWriter, r *http.Request) {
+	userId, ok := h.authorizeUser(w, r)
+	if !ok {
+		return
+	}
+	search, err := h.searches.Get(r.Context(), userId, mux.Vars(r)["searchId"])
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "saved search not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		utils.HandleResponseErr(w, h.logger, "GetSavedSearch failed: ", err)
+		return
+	}
+	utils.RenderJson(w, domain.SavedSearchResponse{Search: *search})
+}
+
+// UpdateSavedSearch replaces the search. Changing what it matches starts
+// over, so listings matched already aren't reported as new.
+func (h *FeedHandler) UpdateSavedSearch(w http.ResponseWriter, r *http.Request) {
+	log := h.logger.With(slog.String("op", "UpdateSavedSearch"))
+
+	userId, ok := h.authorizeUser(w, r)
+	if !ok {
+		return
+	}
+	body, ok := decodeSavedSearch(w, r)
+	if !ok {
+		return
+	}
+
+	search, err := h.searches.Get(r.Context(), userId, mux.Vars(r)["searchId"])
+	if err == nil {
+		search.Name, search.Query, search.Filter, search.Sort = body.Name, body.Query, body.Filter, body.Sort
+		log.Info("saving search", slog.Any("search", search))
+		err = h.searches.Save(r.Context(), search)
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "saved search not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		utils.HandleResponseErr(w, h.logger, "UpdateSavedSearch failed: ", err)
+		return
+	}
+	utils.RenderJson(w, domain.SavedSearchResponse{Search: *search})
+}
+
+func (h *FeedHandler) DeleteSavedSearch(w http.ResponseWriter, r *http.Request) {
+	userId, ok := h.authorizeUser(w, r)
+	if !ok {
+		return
+	}
+	removed, err := h.searches.Delete(r.Context(), userId, mux.Vars(r)["searchId"])
+	if err != nil {
+		utils.HandleResponseErr(w, h.logger, "DeleteSavedSearch failed: ", err)
+		return
+	}
+	if !removed {
+		http.Error(w, "saved search not found", http.StatusNotFound)
+		return
+	}
+	utils.RenderJson(w, domain.DeleteSavedSearchResponse{Success: true})
+}
+
+// ListNotifications pages through the user in-app inbox, latest first.
+func (h *FeedHandler) ListNotifications(w http.ResponseWriter, r *http.Request) {
+	userId, ok := h.authorizeUser(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	pageNum, _ := strconv.Atoi(q.Get("page_number"))
+	if pageNum < 1 {
+		pageNum = 1
+	}
+	pageSize, _ := strconv.Atoi(q.Get("page_size"))
+	if pageSize < 1 {
+		pageSize = 10
+	}
+
+	list, err := h.inbox.List(r.Context(), userId)
+	if err != nil {
+		utils.HandleResponseErr(w, h.logger, "ListNotifications failed: ", err)
+		return
+	}
+	from := min((pageNum-1)*pageSize, len(list))
+
+	utils.RenderJson(w, domain.ListNotificationsResponse{
+		Notifications: list[from:min(from+pageSize, len(list))],
+		PageMetadata: domain.PageResponseMetadata{
+			TotalItems:  int32(len(list)),
+			TotalPages:  int32((len(list) + pageSize - 1) / pageSize),
+			CurrentPage: int32(pageNum),
+		},
+	})
+}
+
+func (h *FeedHandler) DeleteNotification(w http.ResponseWriter, r *http.Request) {
+	userId, ok := h.authorizeUser(w, r)
+	if !ok {
+		return
+	}
+	removed, err := h.inbox.Delete(r.Context(), userId, mux.Vars(r)["notificationId"])
+	if err != nil {
+		utils.HandleResponseErr(w, h.logger, "DeleteNotification failed: ", err)
+		return
+	}
+	if !removed {
+		http.Error(w, "notification not found", http.StatusNotFound)
+		return
+	}
+	utils.RenderJson(w, domain.DeleteNotificationResponse{Success: true})
+}
+
+// watchSearches re-runs saved searches every interval until ctx is done.
+func (h *FeedHandler) watchSearches(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		searches, err := h.searches.All(ctx)
+		if err != nil {
+			h.logger.Error("can't load saved searches", slog.Any("error", err))
+			continue
+		}
+		for _, s := range searches {
+			if err = h.runSavedSearch(ctx, &s); err != nil {
+				h.logger.Warn("saved search run failed", slog.String("search", s.SearchId), slog.Any("error", err))
+			}
+		}
+	}
+}
+
+// runSavedSearch matches the first SCAN_LIMIT listings of the search and
+// notifies the user of ones the previous run didn't match. The first run
+// only remembers what matches.
+func (h *FeedHandler) runSavedSearch(ctx context.Context, s *domain.SavedSearch) error {
+	ctx, cancel := context.WithTimeout(ctx, searchRunTimeout)
+	defer cancel()
+
+	sort, err := mappers.ToSort(url.Values{"sort": {s.Sort}})
+	if err != nil {
+		return err
+	}
+	fetch := h.listPages
+	if s.Query != "" {
+		fetch = h.searchPages(s.Query)
+	}
+
+	matched, _, _, err := h.filterListings(ctx, fetch, newListingOrder(sort), s.Filter, 1, h.scanLimit, nil)
+	if err != nil {
+		return err
+	}
+	seen, ran, err := h.searches.Seen(ctx, s.SearchId)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	seenSet := make(map[string]bool, len(seen))
+	for _, id := range seen {
+		seenSet[id] = true
+	}
+	ids := make([]string, len(matched))
+	var fresh []domain.CarListing
+	for i, p := range matched {
+		ids[i] = p.listing.ListingId
+		if ran && !seenSet[ids[i]] {
+			fresh = append(fresh, p.listing)
+		}
+	}
+
+	if len(fresh) > 0 {
+		n := &domain.Notification{
+			NotificationId: uuid.NewString(),
+			UserId:         s.UserId,
+			Kind:           domain.NotifySavedSearch,
+			SubjectId:      s.SearchId,
+			Title:          fmt.Sprintf("New listings for %q: %d", s.Name, len(fresh)),
+			Listings:       fresh[:min(len(fresh), notifyListings)],
+			CreatedAt:      time.Now().UTC(),
+		}
+		if err = h.notifier.Send(ctx, n); err != nil {
+			// seen ids stay as they were, so the next run tries again
+			return err
+		}
+	}
+
+	return h.searches.SetSeen(ctx, s.SearchId, ids, time.Now().UTC())
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"cmp"
+
+	feed "github.com/nikita-itmo-gh-acc/car_estimator_api_contracts/gen/feed_v1"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/domain"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/mappers"
+)
+
+var reversedSort = map[feed.SortBy]feed.SortBy{
+	feed.SortBy_SORT_DATE_DESC:    feed.SortBy_SORT_DATE_ASC,
+	feed.SortBy_SORT_DATE_ASC:     feed.SortBy_SORT_DATE_DESC,
+	feed.SortBy_SORT_PRICE_DESC:   feed.SortBy_SORT_PRICE_ASC,
+	feed.SortBy_SORT_PRICE_ASC:    feed.SortBy_SORT_PRICE_DESC,
+	feed.SortBy_SORT_MILEAGE_DESC: feed.SortBy_SORT_MILEAGE_ASC,
+	feed.SortBy_SORT_MILEAGE_ASC:  feed.SortBy_SORT_MILEAGE_DESC,
+}
+
+// listingOrder is the order listings are served in. Feed service sorts by the
+// first key when it supports it, the rest is sorted on the gateway within runs
+// of listings the backend considers equal, and listing id breaks the ties.
+type listingOrder struct {
+	sort     domain.Sort
+	backend  feed.SortBy
+	grouped  int
+	backward bool
+}
+
+func newListingOrder(sort domain.Sort) listingOrder {
+	o := listingOrder{sort: sort}
+	if len(sort) > 0 {
+		if sortBy, ok := mappers.ToBackendSort(sort[0]); ok {
+			o.backend, o.grouped = sortBy, 1
+		}
+	}
+	return o
+}
+
+func (o listingOrder) reversed() listingOrder {
+	o.backward = !o.backward
+	if o.grouped > 0 {
+		o.backend = reversedSort[o.backend]
+	}
+	return o
+}
+
+func (o listingOrder) sorted() bool {
+	return len(o.sort) > 0
+}
+
+// direct tells whether backend pages come in this order already, up to ties.
+func (o listingOrder) direct() bool {
+	return len(o.sort) == o.grouped
+}
+
+// compareGroups compares listings by the keys backend sorts on.
+func (o listingOrder) compareGroups(a, b *domain.CarListing) int {
+	c := o.sort[:o.grouped].Compare(a, b)
+	if o.backward {
+		return -c
+	}
+	return c
+}
+
+func (o listingOrder) compare(a, b *domain.CarListing) int {
+	c := cmp.Or(o.sort.Compare(a, b), cmp.Compare(a.ListingId, b.ListingId))
+	if o.backward {
+		return -c
+	}
+	return c
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+	feed "github.com/nikita-itmo-gh-acc/car_estimator_api_contracts/gen/feed_v1"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/domain"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/mappers"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/utils"
+)
+
+var errNoPrice = errors.New("prediction service returned no price")
+
+// valuate compares asking price of the listing with the price predicted for
+// its car sold this year.
+func (h *FeedHandler) valuate(ctx context.Context, l *domain.CarListing) (*domain.Valuation, error) {
+	params := mappers.ToPredictionRequest(l, time.Now().Year())
+	resp, err := h.predictions.Predict(ctx, mappers.ToPredictRequest(params))
+	if err != nil {
+		return nil, err
+	}
+	if resp.GetPrice() <= 0 {
+		return nil, errNoPrice
+	}
+
+	v := &domain.Valuation{
+		ListingId:        l.ListingId,
+		AskingPrice:      l.Price,
+		ListingValuation: domain.ListingValuation{PredictedPrice: float64(resp.GetPrice())},
+		Inputs:           *params,
+	}
+	h.valuation.Rate(v)
+	return v, nil
+}
+
+// GetListingValuation rates asking price of the listing against the model
+// prediction for its car.
+func (h *FeedHandler) GetListingValuation(w http.ResponseWriter, r *http.Request) {
+	log := h.logger.With(slog.String("op", "GetListingValuation"))
+
+	listingId := mux.Vars(r)["listingId"]
+	log.Info("→ gRPC GetListing", slog.String("id", listingId))
+	grpcResp, err := h.client.GetListing(r.Context(), &feed.GetListingRequest{ListingId: listingId})
+	if err != nil {
+		utils.HandleResponseErr(w, h.logger, "GetListingValuation failed: ", err)
+		return
+	}
+
+	log.Info("→ gRPC Predict", slog.String("id", listingId))
+	v, err := h.valuate(r.Context(), mappers.ToDomain(grpcResp.GetListing()))
+	if errors.Is(err, errNoPrice) {
+		http.Error(w, "GetListingValuation failed: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	if err != nil {
+		utils.HandleResponseErr(w, h.logger, "GetListingValuation failed: ", err)
+		return
+	}
+
+	utils.RenderJson(w, domain.ValuationResponse{Valuation: *v})
+}
+
+// enrichValuations sets valuation of listings, running at most
+// valuationConcurrency predictions at a time within valuationBudget. Listings
+// not valued in time or failed to are left without one, and counted.
+func (h *FeedHandler) enrichValuations(ctx context.Context, listings []domain.CarListing) int32 {
+	ctx, cancel := context.WithTimeout(ctx, h.valuationBudget)
+	defer cancel()
+
+	var (
+		wg      sync.WaitGroup
+		missing atomic.Int32
+		sem     = make(chan struct{}, h.valuationConcurrency)
+	)
+	for i := range listings {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			missing.Add(int32(len(listings) - i))
+			wg.Wait()
+			return missing.Load()
+		}
+
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			v, err := h.valuate(ctx, &listings[i])
+			if err != nil {
+				h.logger.Debug("listing left without valuation", slog.String("id", listings[i].ListingId), slog.Any("error", err))
+				missing.Add(1)
+				return
+			}
+			listings[i].Valuation = &v.ListingValuation
+		}()
+	}
+	wg.Wait()
+	return missing.Load()
+}
@@ -0,0 +1,221 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	feed "github.com/nikita-itmo-gh-acc/car_estimator_api_contracts/gen/feed_v1"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/domain"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/mappers"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/utils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const watchCheckTimeout = 10 * time.Second
+
+// watchUser returns the user of request access token. Without
+// ACCESS_TOKEN_SECRET tokens can't be verified, so user_id param names the
+// user instead. On failure it writes 400 or 401 and returns false.
+func (h *FeedHandler) watchUser(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if h.tokens == nil {
+		userId := r.URL.Query().Get("user_id")
+		if userId == "" {
+			http.Error(w, "user_id param is required", http.StatusBadRequest)
+			return "", false
+		}
+		return userId, true
+	}
+	userId := h.currentUser(r)
+	if userId == "" {
+		http.Error(w, "valid access token is required", http.StatusUnauthorized)
+		return "", false
+	}
+	return userId, true
+}
+
+// WatchListing subscribes the user to price and status changes of the listing.
+func (h *FeedHandler) WatchListing(w http.ResponseWriter, r *http.Request) {
+	log := h.logger.With(slog.String("op", "WatchListing"))
+
+	listingId := mux.Vars(r)["listingId"]
+	userId, ok := h.watchUser(w, r)
+	if !ok {
+		return
+	}
+
+	log.Info("→ gRPC GetListing", slog.String("id", listingId))
+	grpcResp, err := h.client.GetListing(r.Context(), &feed.GetListingRequest{ListingId: listingId})
+	if err != nil {
+		utils.HandleResponseErr(w, h.logger, "WatchListing failed: ", err)
+		return
+	}
+
+	snapshot, err := h.watches.Add(r.Context(), userId, domain.ListingSnapshot{
+		ListingId: listingId,
+		Price:     grpcResp.GetListing().GetPrice(),
+		Status:    grpcResp.GetListing().GetStatus(),
+		CheckedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		utils.HandleResponseErr(w, h.logger, "WatchListing failed: ", err)
+		return
+	}
+
+	utils.RenderJson(w, domain.WatchListingResponse{ListingId: listingId, UserId: userId, Snapshot: snapshot})
+}
+
+func (h *FeedHandler) UnwatchListing(w http.ResponseWriter, r *http.Request) {
+	listingId := mux.Vars(r)["listingId"]
+	userId, ok := h.watchUser(w, r)
+	if !ok {
+		return
+	}
+
+	removed, err := h.watches.Remove(r.Context(), userId, listingId)
+	if err != nil {
+		utils.HandleResponseErr(w, h.logger, "UnwatchListing failed: ", err)
+		return
+	}
+	if !removed {
+		http.Error(w, "listing isn't watched by the user", http.StatusNotFound)
+		return
+	}
+	utils.RenderJson(w, domain.UnwatchListingResponse{Success: true})
+}
+
+// StreamNotifications sends user notifications as Server-Sent Events while
+// the client stays connected.
+func (h *FeedHandler) StreamNotifications(w http.ResponseWriter, r *http.Request) {
+	if h.events == nil {
+		http.Error(w, "notification stream is disabled, add sse to NOTIFY_SINKS", http.StatusNotFound)
+		return
+	}
+
+	userId, ok := h.authorizeUser(w, r)
+	if !ok {
+		return
+	}
+	notifications, cancel := h.events.Subscribe(userId)
+	defer cancel()
+
+	sse, err := newSSEWriter(w)
+	if err != nil {
+		return
+	}
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+
+	ctx := untimedContext(r)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			err = sse.Heartbeat()
+		case n := <-notifications:
+			err = sse.Event(n.NotificationId, n.Kind, n)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// watchListings checks watched listings every interval until ctx is done.
+func (h *FeedHandler) watchListings(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		watched, err := h.watches.All(ctx)
+		if err != nil {
+			h.logger.Error("can't load watched listings", slog.Any("error", err))
+			continue
+		}
+		for _, wl := range watched {
+			if err = h.checkWatched(ctx, &wl); err != nil {
+				h.logger.Warn("watched listing check failed", slog.String("id", wl.ListingId), slog.Any("error", err))
+			}
+		}
+	}
+}
+
+// checkWatched compares the listing with its snapshot and notifies watching
+// users of price and status changes, or of the listing being removed.
+// Snapshot is updated even if some notifications fail, so users who got one
+// don't get it again.
+func (h *FeedHandler) checkWatched(ctx context.Context, wl *domain.WatchedListing) error {
+	ctx, cancel := context.WithTimeout(ctx, watchCheckTimeout)
+	defer cancel()
+
+	resp, err := h.client.GetListing(ctx, &feed.GetListingRequest{ListingId: wl.ListingId})
+	if status.Code(err) == codes.NotFound {
+		h.notifyWatchers(ctx, wl, domain.Notification{
+			Kind:  domain.NotifyListingRemoved,
+			Title: fmt.Sprintf("Listing %s was removed", wl.ListingId),
+		})
+		return h.watches.Drop(ctx, wl.ListingId)
+	}
+	if err != nil {
+		return err
+	}
+
+	listing := mappers.ToDomain(resp.GetListing())
+	if listing.Price == wl.Price && listing.Status == wl.Status {
+		return nil
+	}
+
+	name := fmt.Sprintf("%d %s %s", listing.Year, listing.Make, listing.ModelName)
+	n := domain.Notification{
+		Kind:     domain.NotifyStatusChange,
+		Title:    fmt.Sprintf("%s is now %s", name, listing.Status),
+		Listings: []domain.CarListing{*listing},
+		Change: &domain.ListingChange{
+			OldPrice:  wl.Price,
+			NewPrice:  listing.Price,
+			OldStatus: wl.Status,
+			NewStatus: listing.Status,
+		},
+	}
+	switch {
+	case listing.Price < wl.Price:
+		n.Kind, n.Title = domain.NotifyPriceChange, fmt.Sprintf("Price of %s dropped from %g to %g", name, wl.Price, listing.Price)
+	case listing.Price > wl.Price:
+		n.Kind, n.Title = domain.NotifyPriceChange, fmt.Sprintf("Price of %s rose from %g to %g", name, wl.Price, listing.Price)
+	}
+	h.notifyWatchers(ctx, wl, n)
+
+	return h.watches.Update(ctx, domain.ListingSnapshot{
+		ListingId: wl.ListingId,
+		Price:     listing.Price,
+		Status:    listing.Status,
+		CheckedAt: time.Now().UTC(),
+	})
+}
+
+// notifyWatchers sends every watching user a copy of n, as sinks like the
+// SSE stream keep notifications they are given.
+func (h *FeedHandler) notifyWatchers(ctx context.Context, wl *domain.WatchedListing, n domain.Notification) {
+	for _, userId := range wl.UserIds {
+		userN := n
+		userN.NotificationId = uuid.NewString()
+		userN.UserId = userId
+		userN.SubjectId = wl.ListingId
+		userN.CreatedAt = time.Now().UTC()
+		if err := h.notifier.Send(ctx, &userN); err != nil {
+			h.logger.Warn("watch notification failed", slog.String("id", wl.ListingId), slog.String("user", userId), slog.Any("error", err))
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+
+	feed "github.com/nikita-itmo-gh-acc/car_estimator_api_contracts/gen/feed_v1"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/domain"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/store"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// watchedFeed answers GetListing with its listings, NotFound for the rest.
+type watchedFeed struct {
+	feed.FeedServiceClient
+	listings map[string]*feed.CarListing
+}
+
+func (f *watchedFeed) GetListing(_ context.Context, in *feed.GetListingRequest, _ ...grpc.CallOption) (*feed.GetListingResponse, error) {
+	l, ok := f.listings[in.ListingId]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "listing not found")
+	}
+	return &feed.GetListingResponse{Listing: l}, nil
+}
+
+// recordedSink keeps notifications it is sent.
+type recordedSink struct {
+	mu   sync.Mutex
+	sent []*domain.Notification
+}
+
+func (s *recordedSink) Send(_ context.Context, n *domain.Notification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, n)
+	return nil
+}
+
+func TestCheckWatched(t *testing.T) {
+	tests := []struct {
+		name      string
+		listing   *feed.CarListing
+		wantKind  string
+		wantTitle string
+	}{
+		{"unchanged", &feed.CarListing{ListingId: "l1", Price: 1000, Status: "active"}, "", ""},
+		{"price dropped", &feed.CarListing{ListingId: "l1", Price: 900, Status: "active"}, domain.NotifyPriceChange, "dropped from 1000 to 900"},
+		{"price rose", &feed.CarListing{ListingId: "l1", Price: 1100, Status: "active"}, domain.NotifyPriceChange, "rose from 1000 to 1100"},
+		{"status changed", &feed.CarListing{ListingId: "l1", Price: 1000, Status: "sold"}, domain.NotifyStatusChange, "is now sold"},
+		{"removed", nil, domain.NotifyListingRemoved, "was removed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			client := &watchedFeed{listings: map[string]*feed.CarListing{}}
+			if tt.listing != nil {
+				client.listings["l1"] = tt.listing
+			}
+			watches, _ := store.NewWatches("")
+			sink := &recordedSink{}
+			h := &FeedHandler{
+				client:   client,
+				logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+				watches:  watches,
+				notifier: sink,
+			}
+
+			snapshot := domain.ListingSnapshot{ListingId: "l1", Price: 1000, Status: "active"}
+			for _, userId := range []string{"u1", "u2"} {
+				watches.Add(ctx, userId, snapshot)
+			}
+			wl := domain.WatchedListing{ListingSnapshot: snapshot, UserIds: []string{"u1", "u2"}}
+			if err := h.checkWatched(ctx, &wl); err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.wantKind == "" {
+				if len(sink.sent) != 0 {
+					t.Errorf("sent %d notifications of an unchanged listing", len(sink.sent))
+				}
+				return
+			}
+			if len(sink.sent) != 2 {
+				t.Fatalf("sent %d notifications, want one per watching user", len(sink.sent))
+			}
+			for i, userId := range []string{"u1", "u2"} {
+				n := sink.sent[i]
+				if n.UserId != userId || n.SubjectId != "l1" || n.Kind != tt.wantKind || !strings.Contains(n.Title, tt.wantTitle) {
+					t.Errorf("notification %d = %+v, want %s %q for %s", i, n, tt.wantKind, tt.wantTitle, userId)
+				}
+			}
+			if sink.sent[0].NotificationId == sink.sent[1].NotificationId {
+				t.Error("users got notifications of the same id")
+			}
+
+			all, _ := watches.All(ctx)
+			switch {
+			case tt.listing == nil && len(all) != 0:
+				t.Errorf("removed listing is still watched: %+v", all)
+			case tt.listing != nil && (len(all) != 1 || all[0].Price != tt.listing.Price || all[0].Status != tt.listing.Status):
+				t.Errorf("snapshot = %+v, want price %g and status %s", all, tt.listing.Price, tt.listing.Status)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/domain"
+)
+
+// jobTracker runs jobs over a number of items, at most concurrency items at
+// a time, and keeps them for ttl once finished.
+type jobTracker[R any] struct {
+	concurrency int
+	ttl         time.Duration
+
+	mu   sync.Mutex
+	jobs map[string]*trackedJob[R]
+}
+
+// trackedJob is a job with the items done so far marked.
+type trackedJob[R any] struct {
+	domain.Job[R]
+	done []bool
+}
+
+func newJobTracker[R any](concurrency int, ttl time.Duration) *jobTracker[R] {
+	return &jobTracker[R]{concurrency: concurrency, ttl: ttl, jobs: map[string]*trackedJob[R]{}}
+}
+
+// start registers a pending job of total items and returns its id.
+func (t *jobTracker[R]) start(total int) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for id, job := range t.jobs {
+		if job.FinishedAt != nil && time.Since(*job.FinishedAt) > t.ttl {
+			delete(t.jobs, id)
+		}
+	}
+
+	id := uuid.NewString()
+	t.jobs[id] = &trackedJob[R]{
+		Job: domain.Job[R]{
+			JobId:     id,
+			Status:    domain.JobPending,
+			Total:     total,
+			CreatedAt: time.Now().UTC(),
+			Results:   make([]R, total),
+		},
+		done: make([]bool, total),
+	}
+	return id
+}
+
+// get returns a copy of the job, safe to render while it runs. Results of
+// items not done yet are left out.
+func (t *jobTracker[R]) get(id string) (domain.Job[R], bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	job, ok := t.jobs[id]
+	if !ok {
+		return domain.Job[R]{}, false
+	}
+	snapshot := job.Job
+	snapshot.Results = make([]R, 0, job.Processed)
+	for i, res := range job.Results {
+		if job.done[i] {
+			snapshot.Results = append(snapshot.Results, res)
+		}
+	}
+	return snapshot, true
+}
+
+func (t *jobTracker[R]) update(id string, fn func(job *trackedJob[R])) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fn(t.jobs[id])
+}
+
+// run does every item of the job with do, which returns the item result and
+// whether it succeeded.
+func (t *jobTracker[R]) run(ctx context.Context, id string, do func(ctx context.Context, i int) (R, bool)) {
+	var total int
+	t.update(id, func(job *trackedJob[R]) {
+		job.Status, total = domain.JobRunning, job.Total
+	})
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, t.concurrency)
+	for i := range total {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			res, ok := do(ctx, i)
+			t.update(id, func(job *trackedJob[R]) {
+				job.Results[i], job.done[i] = res, true
+				job.Processed++
+				if ok {
+					job.Succeeded++
+				} else {
+					job.Failed++
+				}
+			})
+		}()
+	}
+	wg.Wait()
+
+	t.update(id, func(job *trackedJob[R]) {
+		now := time.Now().UTC()
+		job.Status, job.FinishedAt = domain.JobDone, &now
+	})
+}
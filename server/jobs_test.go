@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/domain"
+)
+
+func TestJobTrackerRun(t *testing.T) {
+	jobs := newJobTracker[int](3, time.Hour)
+	id := jobs.start(10)
+
+	var running, peak atomic.Int32
+	jobs.run(context.Background(), id, func(_ context.Context, i int) (int, bool) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		time.Sleep(time.Millisecond)
+		return i * 10, i%4 != 0
+	})
+
+	job, ok := jobs.get(id)
+	if !ok {
+		t.Fatal("job not found")
+	}
+	if job.Status != domain.JobDone || job.FinishedAt == nil {
+		t.Errorf("status = %s, finished at %v, want done", job.Status, job.FinishedAt)
+	}
+	if job.Processed != 10 || job.Succeeded != 7 || job.Failed != 3 {
+		t.Errorf("processed %d, succeeded %d, failed %d, want 10, 7, 3", job.Processed, job.Succeeded, job.Failed)
+	}
+	for i, res := range job.Results {
+		if res != i*10 {
+			t.Fatalf("results = %v, want them in item order", job.Results)
+		}
+	}
+	if peak.Load() > 3 {
+		t.Errorf("%d items ran at once, concurrency is 3", peak.Load())
+	}
+}
+
+func TestJobTrackerSnapshotWhileRunning(t *testing.T) {
+	jobs := newJobTracker[string](1, time.Hour)
+	id := jobs.start(3)
+
+	release := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		jobs.run(context.Background(), id, func(_ context.Context, i int) (string, bool) {
+			if i == 1 {
+				<-release
+			}
+			// a zero result still counts as done
+			return "", true
+		})
+		close(finished)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		job, _ := jobs.get(id)
+		if job.Processed == 1 {
+			if job.Status != domain.JobRunning || len(job.Results) != 1 {
+				t.Errorf("status %s with %d results, want running with 1", job.Status, len(job.Results))
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("first item isn't done")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	<-finished
+
+	if job, _ := jobs.get(id); len(job.Results) != 3 {
+		t.Errorf("%d results of finished job, want 3", len(job.Results))
+	}
+}
+
+func TestJobTrackerExpires(t *testing.T) {
+	jobs := newJobTracker[int](1, time.Millisecond)
+	old := jobs.start(0)
+	jobs.run(context.Background(), old, nil)
+	time.Sleep(5 * time.Millisecond)
+
+	jobs.start(1)
+	if _, ok := jobs.get(old); ok {
+		t.Error("finished job is kept past its ttl")
+	}
+}
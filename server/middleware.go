@@ -0,0 +1,159 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const clientIdleTimeout = 10 * time.Minute
+
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// rateLimiter keeps a token bucket per client IP. Zero limit disables it.
+// X-Forwarded-For tells the client only behind proxies, since any client can
+// set it.
+type rateLimiter struct {
+	mu        sync.Mutex
+	limit     rate.Limit
+	burst     int
+	proxies   []netip.Prefix
+	clients   map[string]*clientLimiter
+	lastSweep time.Time
+}
+
+func newRateLimiter(limit float64, burst int, proxies []netip.Prefix) *rateLimiter {
+	return &rateLimiter{
+		limit:     rate.Limit(limit),
+		burst:     burst,
+		proxies:   proxies,
+		clients:   map[string]*clientLimiter{},
+		lastSweep: time.Now(),
+	}
+}
+
+func (l *rateLimiter) Update(limit float64, burst int, proxies []netip.Prefix) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.limit, l.burst, l.proxies = rate.Limit(limit), burst, proxies
+	for _, c := range l.clients {
+		c.limiter.SetLimit(l.limit)
+		c.limiter.SetBurst(burst)
+	}
+}
+
+func (l *rateLimiter) Allow(client string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.limit == 0 {
+		return true
+	}
+
+	now := time.Now()
+	if now.Sub(l.lastSweep) > clientIdleTimeout {
+		for k, c := range l.clients {
+			if now.Sub(c.lastSeen) > clientIdleTimeout {
+				delete(l.clients, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	c, ok := l.clients[client]
+	if !ok {
+		c = &clientLimiter{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.clients[client] = c
+	}
+	c.lastSeen = now
+
+	return c.limiter.Allow()
+}
+
+func (l *rateLimiter) trusted(addr netip.Addr) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return slices.ContainsFunc(l.proxies, func(p netip.Prefix) bool {
+		return p.Contains(addr.Unmap())
+	})
+}
+
+// client returns IP of the peer, or the one X-Forwarded-For names when the
+// peer is a trusted proxy. The header is read from the right, as proxies
+// append to it, skipping trusted proxies; the first other address is the
+// client.
+func (l *rateLimiter) client(r *http.Request) string {
+	host := r.RemoteAddr
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !l.trusted(addr) {
+		return host
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(forwarded[i]))
+		if err != nil {
+			break
+		}
+		if addr = hop; !l.trusted(hop) {
+			break
+		}
+	}
+	return addr.Unmap().String()
+}
+
+func (l *rateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !l.Allow(l.client(r)) {
+			http.Error(w, "too many requests", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+type requestTimeout struct {
+	timeout atomic.Int64
+}
+
+func (t *requestTimeout) Update(d time.Duration) {
+	t.timeout.Store(int64(d))
+}
+
+type untimedKey struct{}
+
+// untimedContext returns request context without the deadline of
+// requestTimeout, for long-lived responses like exports and event streams.
+func untimedContext(r *http.Request) context.Context {
+	if ctx, ok := r.Context().Value(untimedKey{}).(context.Context); ok {
+		return ctx
+	}
+	return r.Context()
+}
+
+func (t *requestTimeout) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if d := time.Duration(t.timeout.Load()); d > 0 {
+			ctx, cancel := context.WithTimeout(context.WithValue(r.Context(), untimedKey{}, r.Context()), d)
+			defer cancel()
+			r = r.WithContext(ctx)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
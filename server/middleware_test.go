@@ -0,0 +1,54 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestRateLimiterClient(t *testing.T) {
+	proxies := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("192.168.1.1/32")}
+	tests := []struct {
+		name, remote, forwarded, want string
+	}{
+		{"direct client", "203.0.113.5:4000", "", "203.0.113.5"},
+		{"direct client can't spoof", "203.0.113.5:4000", "198.51.100.1", "203.0.113.5"},
+		{"trusted proxy", "10.1.2.3:4000", "198.51.100.1", "198.51.100.1"},
+		{"spoofed hop before the proxy", "10.1.2.3:4000", "1.2.3.4, 198.51.100.1", "198.51.100.1"},
+		{"chain of trusted proxies", "10.1.2.3:4000", "198.51.100.1, 192.168.1.1, 10.9.9.9", "198.51.100.1"},
+		{"only proxies", "10.1.2.3:4000", "10.2.2.2", "10.2.2.2"},
+		{"proxy without header", "10.1.2.3:4000", "", "10.1.2.3"},
+		{"garbage hop", "10.1.2.3:4000", "not-an-ip", "10.1.2.3"},
+		{"IPv6 client", "[2001:db8::1]:4000", "", "2001:db8::1"},
+	}
+	l := newRateLimiter(1, 1, proxies)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remote
+			if tt.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if got := l.client(r); got != tt.want {
+				t.Errorf("client = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimiterIgnoresForwardedFor(t *testing.T) {
+	l := newRateLimiter(1, 1, nil)
+	h := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for i, want := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = "203.0.113.5:4000"
+		r.Header.Set("X-Forwarded-For", netip.AddrFrom4([4]byte{198, 51, 100, byte(i)}).String())
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		if rec.Code != want {
+			t.Errorf("request %d: status = %d, want %d", i, rec.Code, want)
+		}
+	}
+}
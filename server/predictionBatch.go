@@ -0,0 +1,227 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	model "github.com/nikita-itmo-gh-acc/car_estimator_api_contracts/gen/prediction_v1/go"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/domain"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/mappers"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/utils"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	batchJobTTL = time.Hour
+	// batchRunTimeout bounds async batches, which outlive their request
+	batchRunTimeout = 30 * time.Minute
+	batchBodyLimit  = 16 << 20
+)
+
+var errTooManyItems = errors.New("too many items")
+
+// batchPredictor runs prediction batches and keeps their jobs, finished ones
+// for batchJobTTL.
+type batchPredictor struct {
+	concurrency int
+	asyncItems  int
+	maxItems    int
+	jobs        *jobTracker[domain.PredictionBatchItem]
+}
+
+func newBatchPredictor(concurrency, asyncItems, maxItems int) *batchPredictor {
+	return &batchPredictor{
+		concurrency: concurrency,
+		asyncItems:  asyncItems,
+		maxItems:    maxItems,
+		jobs:        newJobTracker[domain.PredictionBatchItem](concurrency, batchJobTTL),
+	}
+}
+
+// batchItem is a parsed request, err is set when it can't be predicted.
+type batchItem struct {
+	params *domain.PredictionRequest
+	err    error
+}
+
+// parse reads JSON array body, CSV body with a header row or CSV uploaded as
+// "file" of a multipart form, validating every item.
+func (b *batchPredictor) parse(w http.ResponseWriter, r *http.Request) ([]batchItem, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	r.Body = http.MaxBytesReader(w, r.Body, batchBodyLimit)
+
+	var items []batchItem
+	add := func(p *domain.PredictionRequest, err error) error {
+		if len(items) == b.maxItems {
+			return errTooManyItems
+		}
+		if err == nil {
+			err = p.Validate()
+		}
+		items = append(items, batchItem{p, err})
+		return nil
+	}
+
+	switch mediaType {
+	case "application/json", "":
+		var raw []json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+			return nil, fmt.Errorf("expected JSON array of prediction requests: %w", err)
+		}
+		for _, msg := range raw {
+			p := &domain.PredictionRequest{}
+			dec := json.NewDecoder(bytes.NewReader(msg))
+			dec.DisallowUnknownFields()
+			err := dec.Decode(p)
+			if err != nil {
+				err = fmt.Errorf("bad JSON: %w", err)
+			}
+			if err = add(p, err); err != nil {
+				return nil, err
+			}
+		}
+
+	case "text/csv", "multipart/form-data":
+		body := io.Reader(r.Body)
+		if mediaType == "multipart/form-data" {
+			file, _, err := r.FormFile("file")
+			if err != nil {
+				return nil, fmt.Errorf("expected CSV as form file \"file\": %w", err)
+			}
+			defer file.Close()
+			body = file
+		}
+
+		reader := csv.NewReader(body)
+		header, err := reader.Read()
+		if err != nil {
+			return nil, fmt.Errorf("can't read CSV header: %w", err)
+		}
+		columns, err := mappers.ToPredictionCSVColumns(header)
+		if err != nil {
+			return nil, err
+		}
+
+		for {
+			record, err := reader.Read()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			var p *domain.PredictionRequest
+			switch {
+			case errors.Is(err, csv.ErrFieldCount):
+			case err != nil:
+				return nil, fmt.Errorf("can't read CSV: %w", err)
+			default:
+				p, err = mappers.ToPredictionRequestFromCSV(columns, record)
+			}
+			if err = add(p, err); err != nil {
+				return nil, err
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported content type %q, expected application/json, text/csv or multipart/form-data", mediaType)
+	}
+
+	if len(items) == 0 {
+		return nil, errors.New("no items to predict")
+	}
+	return items, nil
+}
+
+// run predicts valid items, at most concurrency at a time, converting
+// predictions with toResponse.
+func (b *batchPredictor) run(ctx context.Context, client model.PredictionServiceClient, id string, items []batchItem, toResponse func(context.Context, *model.PredictResponse) *domain.PredictionResponse) {
+	b.jobs.run(ctx, id, func(ctx context.Context, i int) (domain.PredictionBatchItem, bool) {
+		res := domain.PredictionBatchItem{Item: i + 1}
+		if items[i].err != nil {
+			res.Error = items[i].err.Error()
+			return res, false
+		}
+
+		resp, err := client.Predict(ctx, mappers.ToPredictRequest(items[i].params))
+		if err != nil {
+			res.Error = status.Convert(err).Message()
+			return res, false
+		}
+		res.Prediction = toResponse(ctx, resp)
+		return res, true
+	})
+}
+
+// PredictBatch predicts prices of a JSON array or CSV of cars. Batches larger
+// than BATCH_ASYNC_ITEMS items, or any with async=true, run in background and
+// are answered with 202 and the job location. Graphs are linked by graph_url,
+// for as long as the job is kept when it runs in background, inline_graph=true
+// inlines them and omit_graph=true leaves them out.
+func (h *PredictionHandler) PredictBatch(w http.ResponseWriter, r *http.Request) {
+	log := h.logger.With(slog.String("operation", "batch car price prediction"))
+
+	items, err := h.batch.parse(w, r)
+	var maxBytes *http.MaxBytesError
+	switch {
+	case errors.Is(err, errTooManyItems), errors.As(err, &maxBytes):
+		http.Error(w, fmt.Sprintf("batch is too large, at most %d items are allowed: %v", h.batch.maxItems, err), http.StatusRequestEntityTooLarge)
+		return
+	case err != nil:
+		http.Error(w, "bad batch: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	async := r.URL.Query().Get("async") == "true" || len(items) > h.batch.asyncItems
+	inlineGraph := r.URL.Query().Get("inline_graph") == "true"
+	graphTTL := h.graphTTL
+	if async {
+		graphTTL = max(graphTTL, batchRunTimeout+batchJobTTL)
+	}
+	toResponse := func(ctx context.Context, resp *model.PredictResponse) *domain.PredictionResponse {
+		return h.toResponse(ctx, resp, inlineGraph, graphTTL)
+	}
+	if r.URL.Query().Get("omit_graph") == "true" {
+		toResponse = func(_ context.Context, resp *model.PredictResponse) *domain.PredictionResponse {
+			resp.GraphPng = nil
+			return mappers.ToPredictionResponse(resp, "")
+		}
+	}
+	id := h.batch.jobs.start(len(items))
+	log.Info("Send batch to the prediction service...", slog.String("job", id), slog.Int("items", len(items)))
+
+	if async {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), batchRunTimeout)
+			defer cancel()
+			h.batch.run(ctx, h.client, id, items, toResponse)
+		}()
+
+		job, _ := h.batch.jobs.get(id)
+		w.Header().Set("Location", r.URL.Path+"/"+id)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(job)
+		return
+	}
+
+	h.batch.run(r.Context(), h.client, id, items, toResponse)
+	job, _ := h.batch.jobs.get(id)
+	utils.RenderJson(w, job)
+}
+
+func (h *PredictionHandler) GetBatchJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.batch.jobs.get(mux.Vars(r)["jobId"])
+	if !ok {
+		http.Error(w, "prediction batch job not found", http.StatusNotFound)
+		return
+	}
+	utils.RenderJson(w, job)
+}
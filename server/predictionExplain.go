@@ -0,0 +1,111 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"math"
+	"slices"
+	"sync"
+
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/domain"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/mappers"
+)
+
+// perturbation changes feature of a request by delta, telling whether the
+// changed request still makes sense.
+type perturbation struct {
+	feature string
+	step    int
+	apply   func(p *domain.PredictionRequest, delta int) bool
+}
+
+var perturbations = []perturbation{
+	{feature: "year", step: 1, apply: func(p *domain.PredictionRequest, delta int) bool {
+		p.Year += delta
+		return p.YearSell == 0 || p.Year <= p.YearSell
+	}},
+	{feature: "odometer", step: 10000, apply: func(p *domain.PredictionRequest, delta int) bool {
+		p.Odometer += delta
+		return p.Odometer >= 0
+	}},
+	{feature: "hp", step: 10, apply: func(p *domain.PredictionRequest, delta int) bool {
+		p.Hp += delta
+		return p.Hp > 0
+	}},
+}
+
+// explain makes a sensitivity explanation of price predicted for params,
+// re-predicting it with every perturbation one step up and down. The
+// prediction service doesn't explain its predictions itself. Returns nil when
+// none of the re-predictions succeeded.
+func (h *PredictionHandler) explain(ctx context.Context, params *domain.PredictionRequest, price int) *domain.PredictionExplanation {
+	// prices[i][0] is perturbations[i] one step up, prices[i][1] one step down
+	var (
+		wg     sync.WaitGroup
+		prices = make([][2]*int, len(perturbations))
+	)
+	for i, pert := range perturbations {
+		for side, delta := range []int{pert.step, -pert.step} {
+			changed := *params
+			if !pert.apply(&changed, delta) {
+				continue
+			}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				resp, err := h.client.Predict(ctx, mappers.ToPredictRequest(&changed))
+				if err != nil {
+					h.logger.Debug("perturbed prediction failed", slog.String("feature", pert.feature), slog.Any("error", err))
+					return
+				}
+				p := int(resp.GetPrice())
+				prices[i][side] = &p
+			}()
+		}
+	}
+	wg.Wait()
+
+	e := &domain.PredictionExplanation{Source: domain.ExplanationSensitivity, PriceLow: price, PriceHigh: price}
+	for i, pert := range perturbations {
+		up, down := prices[i][0], prices[i][1]
+		var change int
+		switch {
+		case up != nil && down != nil:
+			change = (*up - *down) / 2
+		case up != nil:
+			change = *up - price
+		case down != nil:
+			change = price - *down
+		default:
+			continue
+		}
+		e.Contributions = append(e.Contributions, domain.FeatureContribution{Feature: pert.feature, Step: pert.step, PriceChange: change})
+
+		for _, p := range prices[i] {
+			if p != nil {
+				e.PriceLow, e.PriceHigh = min(e.PriceLow, *p), max(e.PriceHigh, *p)
+			}
+		}
+	}
+	if len(e.Contributions) == 0 {
+		return nil
+	}
+
+	slices.SortStableFunc(e.Contributions, func(a, b domain.FeatureContribution) int {
+		return cmp.Compare(abs(b.PriceChange), abs(a.PriceChange))
+	})
+	if price > 0 {
+		spread := float64(e.PriceHigh-e.PriceLow) / float64(2*price)
+		e.Confidence = math.Round(max(0, 1-spread)*100) / 100
+	}
+	return e
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
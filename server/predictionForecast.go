@@ -0,0 +1,114 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/chart"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/domain"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/mappers"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/utils"
+	"google.golang.org/grpc/status"
+)
+
+// Forecast predicts prices of a car for every year from from_year to
+// to_year, its odometer growing by annual_mileage a year. The car is read
+// from query params with GET and from JSON body with POST. format=svg or
+// format=png renders the forecast as a chart instead of JSON.
+func (h *PredictionHandler) Forecast(w http.ResponseWriter, r *http.Request) {
+	log := h.logger.With(slog.String("operation", "car price forecast"))
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "svg" && format != "png" {
+		http.Error(w, "unknown format "+format+", expected json, svg or png", http.StatusBadRequest)
+		return
+	}
+
+	req := new(domain.ForecastRequest)
+	var err error
+	if r.Method == http.MethodGet {
+		req, err = mappers.ToForecastRequest(r.URL.Query())
+	} else {
+		err = utils.ParseJson(r.Body, req)
+	}
+	if err != nil {
+		http.Error(w, "failed to decode forecast request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	req.SetDefaults(time.Now().Year())
+	if err = req.Validate(); err != nil {
+		http.Error(w, "bad forecast request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	log.Info("Send forecast years to the prediction service...", slog.Any("params", req))
+	points, err := h.forecast(r.Context(), req)
+
+	var line chart.Line
+	for _, p := range points {
+		if p.Error == "" {
+			line.X, line.Y = append(line.X, p.Year), append(line.Y, p.Price)
+		}
+	}
+	if len(line.X) == 0 {
+		utils.HandleResponseErr(w, h.logger, "forecast failed - ", err)
+		return
+	}
+
+	line.Title = req.Make + " " + req.Model + " price forecast"
+	line.XLabel, line.YLabel = "sale year", "price"
+	switch format {
+	case "svg":
+		w.Header().Set("Content-Type", "image/svg+xml")
+		err = chart.WriteSVG(w, line)
+	case "png":
+		w.Header().Set("Content-Type", "image/png")
+		err = chart.WritePNG(w, line)
+	default:
+		utils.RenderJson(w, domain.ForecastResponse{Points: points})
+	}
+	if err != nil {
+		log.Error("can't render forecast chart", slog.Any("error", err))
+	}
+}
+
+// forecast predicts every year of req, at most batch concurrency at a time.
+// err is the first prediction error, if any.
+func (h *PredictionHandler) forecast(ctx context.Context, req *domain.ForecastRequest) (points []domain.ForecastPoint, err error) {
+	points = make([]domain.ForecastPoint, req.ToYear-req.FromYear+1)
+
+	var (
+		wg   sync.WaitGroup
+		once sync.Once
+	)
+	sem := make(chan struct{}, h.batch.concurrency)
+	for i := range points {
+		params := req.PredictionRequest
+		params.YearSell = req.FromYear + i
+		params.Odometer += *req.AnnualMileage * i
+		points[i] = domain.ForecastPoint{Year: params.YearSell, Odometer: params.Odometer}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			resp, predictErr := h.client.Predict(ctx, mappers.ToPredictRequest(&params))
+			if predictErr != nil {
+				points[i].Error = status.Convert(predictErr).Message()
+				once.Do(func() { err = predictErr })
+				return
+			}
+			points[i].Price = int(resp.GetPrice())
+		}()
+	}
+	wg.Wait()
+	return points, err
+}
@@ -1,17 +1,21 @@
 package server
 
 import (
-	"encoding/base64"
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"time"
 
 	"google.golang.org/grpc"
 
 	"github.com/gorilla/mux"
 	model "github.com/nikita-itmo-gh-acc/car_estimator_api_contracts/gen/prediction_v1/go"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/blobs"
 	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/domain"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/mappers"
 	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/utils"
 )
 
@@ -19,14 +23,26 @@ type PredictionHandler struct {
 	r *mux.Router
 	logger *slog.Logger
 	client model.PredictionServiceClient
+
+	session sessionOptions
+	batch   *batchPredictor
+	graphs   blobs.Store
+	graphTTL time.Duration
+	images   *imageProxy
 }
 
-func (h *PredictionHandler) setupgRPC(conn *grpc.ClientConn) {
+func (h *PredictionHandler) setupgRPC(conn grpc.ClientConnInterface) {
 	h.client = model.NewPredictionServiceClient(conn)
 }
 
 func (h *PredictionHandler) setupRoutes() {
 	h.r.HandleFunc("", h.PredictionHandler).Methods("POST")
+	h.r.HandleFunc("/ws", h.PredictionSession).Methods("GET")
+	h.r.HandleFunc("/forecast", h.Forecast).Methods("GET", "POST")
+	h.r.HandleFunc("/batch", h.PredictBatch).Methods("POST")
+	h.r.HandleFunc("/batch/{jobId}", h.GetBatchJob).Methods("GET")
+	h.r.HandleFunc("/graphs/{graphId:[0-9a-f]+}.png", h.GetGraph).Methods("GET").Name("prediction-graph")
+	h.r.HandleFunc("/images/proxy", h.ProxyImage).Methods("GET").Name("image-proxy")
 	h.r.HandleFunc("/images/{make}/{model}/{year}", h.GetImagesHandler).Methods("GET")
 }
 
@@ -60,6 +76,11 @@ func (h *PredictionHandler) GetImagesHandler(w http.ResponseWriter, r *http.Requ
 		http.Error(w, "year param has wrong format", http.StatusBadRequest)
 		return
 	}
+	preset, err := imagePreset(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	response, err := h.client.GetImages(r.Context(), &model.ImagesRequest{
 		Make: carInfo["make"],
@@ -77,7 +98,7 @@ func (h *PredictionHandler) GetImagesHandler(w http.ResponseWriter, r *http.Requ
 	)
 
 	utils.RenderJson(w, domain.ImageResponse{
-		Urls: response.PhotoUrls,
+		Urls: h.proxyURLs(response.PhotoUrls, preset),
 	})
 }
 
@@ -97,32 +118,80 @@ func (h *PredictionHandler) PredictionHandler(w http.ResponseWriter, r *http.Req
 		slog.Any("params", params),
 	)
 
-	response, err := h.client.Predict(r.Context(), &model.PredictRequest{
-		Make: params.Make,
-		Model: params.Model,
-		Year: int32(params.Year),
-		Hp: int32(params.Hp),
-		Body: params.Body,
-		Yearsell: int32(params.YearSell),
-		Odometer: int32(params.Odometer),
-		Color: params.Color,
-	})
+	response, err := h.client.Predict(r.Context(), mappers.ToPredictRequest(params))
 
 	if err != nil {
 		utils.HandleResponseErr(w, h.logger, "prediction operation failed - ", err)
 		return
 	}
 
-	imageB64 := base64.StdEncoding.EncodeToString(response.GraphPng)
-	
 	log.Info(
 		"Successfully received prediction from the model!",
 	)
 
-	utils.RenderJson(w, &domain.PredictionResponse{
-		Price: int(response.GetPrice()),
-		SellCount: int(response.GetSellCount()),
-		Urls: response.GetPhotoUrls(),
-		GraphImg: imageB64,
-	})
+	prediction := h.toResponse(r.Context(), response, r.URL.Query().Get("inline_graph") == "true", h.graphTTL)
+	if r.URL.Query().Get("explain") == "true" {
+		prediction.Explanation = h.explain(r.Context(), params, prediction.Price)
+	}
+	utils.RenderJson(w, prediction)
+}
+
+func graphKey(id string) string {
+	return "graphs/" + id + ".png"
+}
+
+// toResponse links the graph of response to GetGraph for graphTTL, or inlines
+// it as base64 with inlineGraph or when the graph can't be stored. Photos are
+// proxied.
+func (h *PredictionHandler) toResponse(ctx context.Context, response *model.PredictResponse, inlineGraph bool, graphTTL time.Duration) *domain.PredictionResponse {
+	prediction := mappers.ToPredictionResponse(response, h.graphURL(ctx, response.GetGraphPng(), inlineGraph, graphTTL))
+	prediction.Urls = h.proxyURLs(prediction.Urls, imageDefault)
+	return prediction
+}
+
+// graphURL stores graph and returns its URL, empty if graph is to be inlined.
+func (h *PredictionHandler) graphURL(ctx context.Context, graph []byte, inlineGraph bool, ttl time.Duration) string {
+	if inlineGraph || len(graph) == 0 {
+		return ""
+	}
+
+	id := blobs.ContentId(graph)
+	u, err := h.r.Get("prediction-graph").URLPath("graphId", id)
+	if err == nil {
+		err = h.graphs.Put(ctx, graphKey(id), &blobs.Blob{Data: graph, ContentType: "image/png", ExpiresAt: time.Now().Add(ttl)})
+	}
+	if err != nil {
+		h.logger.Warn("can't store prediction graph, inlining it", slog.Any("error", err))
+		return ""
+	}
+	return u.String()
+}
+
+// GetGraph serves prediction graphs linked by graph_url until they expire.
+// Graphs are addressed by content, so they never change under an id.
+func (h *PredictionHandler) GetGraph(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["graphId"]
+	graph, err := h.graphs.Get(r.Context(), graphKey(id))
+	if errors.Is(err, blobs.ErrNotFound) {
+		http.Error(w, "graph not found or expired", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		utils.HandleResponseErr(w, h.logger, "can't read prediction graph - ", err)
+		return
+	}
+
+	// stores drop expired graphs, but one can expire between Get and now
+	maxAge := h.graphTTL
+	if !graph.ExpiresAt.IsZero() {
+		maxAge = time.Until(graph.ExpiresAt)
+	}
+	w.Header().Set("ETag", `"`+id+`"`)
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d, immutable", max(0, int(maxAge.Seconds()))))
+	if r.Header.Get("If-None-Match") == `"`+id+`"` {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(graph.Data)
 }
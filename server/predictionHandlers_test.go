@@ -0,0 +1,54 @@
+package server
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/blobs"
+)
+
+// staleGraphs returns graphs expiring at expiresAt, as if they were read
+// just before it.
+type staleGraphs struct {
+	blobs.Store
+	expiresAt time.Time
+}
+
+func (s staleGraphs) Get(context.Context, string) (*blobs.Blob, error) {
+	return &blobs.Blob{Data: []byte("png"), ContentType: "image/png", ExpiresAt: s.expiresAt}, nil
+}
+
+func TestGetGraphMaxAge(t *testing.T) {
+	tests := []struct {
+		name      string
+		expiresIn time.Duration
+		want      string
+	}{
+		{"fresh", time.Hour, "public, max-age=3599, immutable"},
+		{"expired since read", -time.Second, "public, max-age=0, immutable"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := mux.NewRouter()
+			h := &PredictionHandler{
+				r:        r,
+				logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+				graphs:   staleGraphs{expiresAt: time.Now().Add(tt.expiresIn)},
+				graphTTL: time.Hour,
+			}
+			r.HandleFunc("/graphs/{graphId}.png", h.GetGraph)
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/graphs/ab12.png", nil))
+			if rec.Code != http.StatusOK || rec.Header().Get("Cache-Control") != tt.want {
+				t.Errorf("status %d, Cache-Control %q, want %q", rec.Code, rec.Header().Get("Cache-Control"), tt.want)
+			}
+		})
+	}
+}
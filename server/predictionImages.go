@@ -0,0 +1,313 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"io/fs"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/imaging"
+)
+
+const (
+	imageFetchTimeout = 10 * time.Second
+	imageMaxBytes     = 20 << 20
+	imageMaxAge       = 24 * time.Hour
+	imageDefault      = "card"
+)
+
+var errImageHost = errors.New("image host is not allowed")
+
+// imageProxy serves photos of allowlisted hosts resized to presets as JPEG,
+// caching them under dir. Empty hosts disables it. Once the cache takes
+// more than maxBytes the oldest photos are removed.
+type imageProxy struct {
+	hosts    []string
+	dir      string
+	ttl      time.Duration
+	maxBytes int64
+	client   *http.Client
+	logger   *slog.Logger
+
+	// cached is the size of the cache, as of the last sweep and with photos
+	// cached since
+	cached   atomic.Int64
+	sweeping sync.Mutex
+}
+
+func newImageProxy(hosts []string, dir string, ttl time.Duration, maxBytes int64, logger *slog.Logger) *imageProxy {
+	p := &imageProxy{hosts: hosts, dir: dir, ttl: ttl, maxBytes: maxBytes, logger: logger}
+	p.client = &http.Client{
+		Timeout: imageFetchTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return errors.New("too many redirects")
+			}
+			if !p.allowed(req.URL) {
+				return errImageHost
+			}
+			return nil
+		},
+	}
+	return p
+}
+
+func (p *imageProxy) enabled() bool {
+	return p != nil && len(p.hosts) > 0
+}
+
+// allowed tells whether u is an http(s) URL of a listed host or a subdomain
+// of it.
+func (p *imageProxy) allowed(u *url.URL) bool {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	return slices.ContainsFunc(p.hosts, func(allowed string) bool {
+		return host == allowed || strings.HasSuffix(host, "."+allowed)
+	})
+}
+
+func (p *imageProxy) cachePath(raw, preset string) string {
+	sum := sha256.Sum256([]byte(preset + "\n" + raw))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(p.dir, name[:2], name+".jpg")
+}
+
+// get returns JPEG of the photo at raw in preset, from the cache if it's
+// there.
+func (p *imageProxy) get(ctx context.Context, raw, preset string) ([]byte, error) {
+	path := p.cachePath(raw, preset)
+	if p.dir != "" {
+		if data, err := os.ReadFile(path); err == nil {
+			return data, nil
+		}
+	}
+
+	img, err := p.fetch(ctx, raw)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err = imaging.EncodeJPEG(&buf, imaging.Resize(img, imaging.Presets[preset])); err != nil {
+		return nil, err
+	}
+
+	if p.dir != "" {
+		// cache failures only cost a refetch
+		if err = cacheFile(path, buf.Bytes()); err != nil {
+			p.logger.Warn("can't cache proxied image", slog.Any("error", err))
+		} else if p.cached.Add(int64(buf.Len())) > p.maxBytes {
+			go func() {
+				if err := p.sweep(context.WithoutCancel(ctx)); err != nil {
+					p.logger.Warn("can't sweep cached images", slog.Any("error", err))
+				}
+			}()
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// cacheFile writes data to path through a temporary file, so concurrent
+// readers never see it half written.
+func cacheFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+type imageFetchError struct {
+	status int
+}
+
+func (e *imageFetchError) Error() string {
+	return fmt.Sprintf("image host answered %d", e.status)
+}
+
+func (p *imageProxy) fetch(ctx context.Context, raw string) (image.Image, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, raw, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "image/webp,image/jpeg,image/png,image/*")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &imageFetchError{status: resp.StatusCode}
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); !strings.HasPrefix(mediaType, "image/") {
+		return nil, fmt.Errorf("image host sent %q, not an image", mediaType)
+	}
+	return imaging.Decode(io.LimitReader(resp.Body, imageMaxBytes))
+}
+
+// sweep removes cached images older than ttl, then the oldest ones until
+// the cache takes at most 90% of maxBytes. A sweep running already is let
+// finish instead.
+func (p *imageProxy) sweep(ctx context.Context) error {
+	if !p.sweeping.TryLock() {
+		return nil
+	}
+	defer p.sweeping.Unlock()
+
+	type cachedImage struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var (
+		cached []cachedImage
+		size   int64
+	)
+	cutoff := time.Now().Add(-p.ttl)
+	err := filepath.WalkDir(p.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || strings.HasPrefix(d.Name(), ".tmp-") {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		info, err := d.Info()
+		switch {
+		case err != nil:
+		case info.ModTime().Before(cutoff):
+			os.Remove(path)
+		default:
+			cached = append(cached, cachedImage{path: path, size: info.Size(), modTime: info.ModTime()})
+			size += info.Size()
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if size > p.maxBytes {
+		slices.SortFunc(cached, func(a, b cachedImage) int { return a.modTime.Compare(b.modTime) })
+		for _, c := range cached {
+			if size <= p.maxBytes/10*9 {
+				break
+			}
+			if err := os.Remove(c.path); err == nil || errors.Is(err, fs.ErrNotExist) {
+				size -= c.size
+			}
+		}
+	}
+	p.cached.Store(size)
+	return nil
+}
+
+// proxyURLs points allowlisted photo URLs at ProxyImage in preset, the
+// others are left as they are.
+func (h *PredictionHandler) proxyURLs(urls []string, preset string) []string {
+	if !h.images.enabled() {
+		return urls
+	}
+	route, err := h.r.Get("image-proxy").URLPath()
+	if err != nil {
+		return urls
+	}
+
+	proxied := make([]string, len(urls))
+	for i, raw := range urls {
+		proxied[i] = raw
+		if u, err := url.Parse(raw); err == nil && h.images.allowed(u) {
+			proxied[i] = route.Path + "?" + url.Values{"url": {raw}, "preset": {preset}}.Encode()
+		}
+	}
+	return proxied
+}
+
+// imagePreset reads preset query param of r, which defaults to card.
+func imagePreset(r *http.Request) (string, error) {
+	preset := r.URL.Query().Get("preset")
+	if preset == "" {
+		return imageDefault, nil
+	}
+	if _, ok := imaging.Presets[preset]; !ok {
+		return "", fmt.Errorf("unknown preset %q, expected thumb, card or full", preset)
+	}
+	return preset, nil
+}
+
+// ProxyImage serves the photo at url param of an allowlisted host resized to
+// preset, as JPEG.
+func (h *PredictionHandler) ProxyImage(w http.ResponseWriter, r *http.Request) {
+	log := h.logger.With(slog.String("operation", "proxy car image"))
+
+	if !h.images.enabled() {
+		http.Error(w, "image proxy is disabled", http.StatusNotFound)
+		return
+	}
+	preset, err := imagePreset(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	raw := r.URL.Query().Get("url")
+	u, err := url.Parse(raw)
+	if err != nil || !u.IsAbs() {
+		http.Error(w, "url param must be an absolute URL", http.StatusBadRequest)
+		return
+	}
+	if !h.images.allowed(u) {
+		http.Error(w, errImageHost.Error(), http.StatusForbidden)
+		return
+	}
+
+	data, err := h.images.get(r.Context(), raw, preset)
+	var fetchErr *imageFetchError
+	switch {
+	case errors.As(err, &fetchErr) && fetchErr.status == http.StatusNotFound:
+		http.Error(w, "image not found", http.StatusNotFound)
+		return
+	case errors.Is(err, errImageHost):
+		http.Error(w, "image redirects to a host which is not allowed", http.StatusForbidden)
+		return
+	case err != nil:
+		log.Warn("Can't proxy image", slog.String("url", raw), slog.Any("error", err))
+		http.Error(w, "can't fetch image", http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(imageMaxAge.Seconds())))
+	w.Write(data)
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestProxyImage(t *testing.T) {
+	var photo bytes.Buffer
+	png.Encode(&photo, image.NewRGBA(image.Rect(0, 0, 800, 600)))
+	fetches := 0
+	host := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(photo.Bytes())
+	}))
+	defer host.Close()
+
+	dir := t.TempDir()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	r := mux.NewRouter()
+	h := &PredictionHandler{
+		r:      r.PathPrefix("/prediction").Subrouter(),
+		logger: logger,
+		images: newImageProxy([]string{"127.0.0.1"}, dir, time.Hour, 1<<20, logger),
+	}
+	h.r.HandleFunc("/images/proxy", h.ProxyImage).Name("image-proxy")
+
+	for range 2 {
+		req := httptest.NewRequest(http.MethodGet, "/prediction/images/proxy?url="+url.QueryEscape(host.URL+"/car.png"), nil)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/jpeg" {
+			t.Fatalf("status %d, Content-Type %q", rec.Code, rec.Header().Get("Content-Type"))
+		}
+		config, format, err := image.DecodeConfig(rec.Body)
+		if err != nil || format != "jpeg" || config.Width != 480 || config.Height != 360 {
+			t.Errorf("served %s of %dx%d, %v, want card size JPEG", format, config.Width, config.Height, err)
+		}
+	}
+	if fetches != 1 {
+		t.Errorf("photo fetched %d times, want once", fetches)
+	}
+}
+
+func TestImageProxySweep(t *testing.T) {
+	dir := t.TempDir()
+	p := newImageProxy([]string{"example.com"}, dir, time.Hour, 1000, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	now := time.Now()
+	cache := func(name string, size int, age time.Duration) {
+		t.Helper()
+		path := filepath.Join(dir, name[:2], name)
+		if err := cacheFile(path, make([]byte, size)); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(path, now.Add(-age), now.Add(-age))
+	}
+	cached := func(name string) bool {
+		_, err := os.Stat(filepath.Join(dir, name[:2], name))
+		return err == nil
+	}
+
+	cache("aa-expired.jpg", 100, 2*time.Hour)
+	cache("bb-old.jpg", 400, 30*time.Minute)
+	cache("cc-older.jpg", 400, 40*time.Minute)
+	cache("dd-new.jpg", 300, time.Minute)
+	if err := p.sweep(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// 1100 bytes are left after the expired photo, the oldest ones go until
+	// at most 900 are
+	for name, want := range map[string]bool{"aa-expired.jpg": false, "cc-older.jpg": false, "bb-old.jpg": true, "dd-new.jpg": true} {
+		if cached(name) != want {
+			t.Errorf("%s cached = %v, want %v", name, cached(name), want)
+		}
+	}
+	if got := p.cached.Load(); got != 700 {
+		t.Errorf("cached size = %d, want 700", got)
+	}
+}
@@ -0,0 +1,203 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"golang.org/x/time/rate"
+
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/domain"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/mappers"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/utils"
+)
+
+const (
+	sessionReadLimit  = 64 << 10
+	sessionWriteWait  = 10 * time.Second
+	sessionPongWait   = 60 * time.Second
+	sessionPingPeriod = sessionPongWait * 9 / 10
+)
+
+// sessionOptions configure /prediction/ws connections.
+type sessionOptions struct {
+	// debounce is how long a request waits for a newer one replacing it
+	debounce time.Duration
+	// rate of request messages allowed per connection, 0 disables limiting
+	rate float64
+	// origins allowed besides the gateway own one, "*" allows any
+	origins []string
+}
+
+func (o sessionOptions) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || slices.Contains(o.origins, "*") || slices.Contains(o.origins, origin) {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
+
+type sessionRequest struct {
+	seq    int
+	params *domain.PredictionRequest
+}
+
+// PredictionSession serves live predictions over WebSocket. Client sends
+// PredictionRequest messages, each answered by a prediction or an error.
+// Requests followed by a newer one within the debounce interval are
+// skipped, and a newer request cancels the Predict call of an older one.
+func (h *PredictionHandler) PredictionSession(w http.ResponseWriter, r *http.Request) {
+	log := h.logger.With(slog.String("operation", "prediction session"), slog.String("client", utils.GetClientIp(r)))
+
+	upgrader := websocket.Upgrader{CheckOrigin: h.session.checkOrigin}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// upgrader has answered with an error already
+		log.Warn("WebSocket upgrade failed", slog.Any("error", err))
+		return
+	}
+	defer conn.Close()
+	log.Info("Prediction session opened")
+
+	ctx, cancel := context.WithCancel(untimedContext(r))
+	defer cancel()
+
+	s := &predictionSession{h: h, conn: conn, log: log, inlineGraph: r.URL.Query().Get("inline_graph") == "true"}
+	requests := make(chan sessionRequest)
+	go func() {
+		defer cancel()
+		s.read(ctx, requests)
+	}()
+
+	s.serve(ctx, requests)
+	log.Info("Prediction session closed")
+}
+
+type predictionSession struct {
+	h    *PredictionHandler
+	conn *websocket.Conn
+	log  *slog.Logger
+	// inlineGraph sends graphs as base64 instead of graph_url
+	inlineGraph bool
+
+	writeMu sync.Mutex
+}
+
+func (s *predictionSession) write(msg any) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	s.conn.SetWriteDeadline(time.Now().Add(sessionWriteWait))
+	return s.conn.WriteJSON(msg)
+}
+
+// read decodes request messages until the connection fails, answering
+// malformed and rate limited ones right away.
+func (s *predictionSession) read(ctx context.Context, requests chan<- sessionRequest) {
+	s.conn.SetReadLimit(sessionReadLimit)
+	s.conn.SetReadDeadline(time.Now().Add(sessionPongWait))
+	s.conn.SetPongHandler(func(string) error {
+		return s.conn.SetReadDeadline(time.Now().Add(sessionPongWait))
+	})
+
+	limiter := rate.NewLimiter(rate.Inf, 0)
+	if s.h.session.rate > 0 {
+		limiter = rate.NewLimiter(rate.Limit(s.h.session.rate), max(1, int(s.h.session.rate)))
+	}
+
+	for seq := 1; ; seq++ {
+		_, raw, err := s.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		s.conn.SetReadDeadline(time.Now().Add(sessionPongWait))
+
+		if !limiter.Allow() {
+			s.write(domain.PredictionSessionMessage{Type: domain.SessionRateLimited, Seq: seq, Error: "too many requests, slow down"})
+			continue
+		}
+		params := new(domain.PredictionRequest)
+		if err = json.Unmarshal(raw, params); err != nil {
+			s.write(domain.PredictionSessionMessage{Type: domain.SessionError, Seq: seq, Error: "failed to decode prediction request: " + err.Error()})
+			continue
+		}
+
+		select {
+		case requests <- sessionRequest{seq, params}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// serve debounces requests and runs the latest one, cancelling the call
+// made for a previous request.
+func (s *predictionSession) serve(ctx context.Context, requests <-chan sessionRequest) {
+	debounce := time.NewTimer(0)
+	<-debounce.C
+	ping := time.NewTicker(sessionPingPeriod)
+	defer ping.Stop()
+
+	var (
+		pending  *sessionRequest
+		inflight = func() {}
+		wg       sync.WaitGroup
+	)
+	defer func() {
+		inflight()
+		wg.Wait()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case req := <-requests:
+			pending = &req
+			debounce.Reset(s.h.session.debounce)
+
+		case <-debounce.C:
+			inflight()
+			callCtx, cancel := context.WithCancel(ctx)
+			inflight = cancel
+
+			req := *pending
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				s.predict(callCtx, req)
+			}()
+
+		case <-ping.C:
+			if err := s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(sessionWriteWait)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *predictionSession) predict(ctx context.Context, req sessionRequest) {
+	response, err := s.h.client.Predict(ctx, mappers.ToPredictRequest(req.params))
+	if ctx.Err() != nil {
+		// superseded by a newer request or the session is over
+		return
+	}
+
+	msg := domain.PredictionSessionMessage{Type: domain.SessionPrediction, Seq: req.seq}
+	if err != nil {
+		msg.Type, msg.Error = domain.SessionError, "prediction operation failed - "+err.Error()
+	} else {
+		msg.Prediction = s.h.toResponse(ctx, response, s.inlineGraph, s.h.graphTTL)
+	}
+	if err = s.write(msg); err != nil {
+		s.log.Warn("Can't send prediction", slog.Any("error", err))
+	}
+}
@@ -22,7 +22,7 @@ type ProfileHandler struct {
 	client profile.ProfileServiceClient
 }
 
-func (h *ProfileHandler) setupgRPC(conn *grpc.ClientConn) {
+func (h *ProfileHandler) setupgRPC(conn grpc.ClientConnInterface) {
 	h.client = profile.NewProfileServiceClient(conn)
 }
 
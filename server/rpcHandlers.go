@@ -0,0 +1,55 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/transcoding"
+)
+
+const discoveryTimeout = 5 * time.Second
+
+// RPCHandler exposes allowlisted methods of one backend under /rpc/{service}/{method},
+// discovered by server reflection or read from a descriptor set file.
+type RPCHandler struct {
+	r             *mux.Router
+	logger        *slog.Logger
+	backend       string
+	conn          grpc.ClientConnInterface
+	descriptorSet string
+	allowlist     transcoding.Allowlist
+}
+
+func (h *RPCHandler) setupgRPC(conn grpc.ClientConnInterface) {
+	h.conn = conn
+}
+
+func (h *RPCHandler) setupRoutes() {
+	log := h.logger.With(slog.String("op", "discover"), slog.String("backend", h.backend))
+
+	var (
+		services []protoreflect.ServiceDescriptor
+		err      error
+	)
+	if h.descriptorSet != "" {
+		services, err = transcoding.LoadDescriptorSet(h.descriptorSet)
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), discoveryTimeout)
+		services, err = transcoding.Discover(ctx, h.conn)
+		cancel()
+	}
+	if err != nil {
+		log.Error("services discovery failed", slog.Any("error", err))
+		return
+	}
+
+	for _, rule := range transcoding.RPCRules(services, h.allowlist) {
+		h.r.HandleFunc(rule.MuxPattern(), transcode(h.logger, h.conn, rule)).Methods(rule.HttpMethod)
+		log.Info("RPC route registered", slog.String("rule", rule.String()))
+	}
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -10,12 +11,24 @@ import (
 	"google.golang.org/grpc/credentials/insecure"
 
 	"github.com/gorilla/mux"
+	feed "github.com/nikita-itmo-gh-acc/car_estimator_api_contracts/gen/feed_v1"
+	model "github.com/nikita-itmo-gh-acc/car_estimator_api_contracts/gen/prediction_v1/go"
+	profile "github.com/nikita-itmo-gh-acc/car_estimator_api_contracts/gen/profile_v1"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/auth"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/blobs"
 	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/config"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/domain"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/notify"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/pagination"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/store"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/transcoding"
 )
 
+const sweepInterval = 10 * time.Minute
+
 type IHandler interface {
 	setupRoutes()
-	setupgRPC(conn *grpc.ClientConn)
+	setupgRPC(conn grpc.ClientConnInterface)
 }
 
 type Server struct {
@@ -23,44 +36,259 @@ type Server struct {
 	port string
 	logger *slog.Logger
 	handlers map[string]IHandler
+	backends map[string]*Backend
+	limiter *rateLimiter
+	timeout *requestTimeout
+	// background are jobs running along with the server, like notification workers
+	background []func(ctx context.Context)
 }
 
-func NewServer(conf *config.Config, logger *slog.Logger) *Server {
+// NewServer fails when backends can't be connected to or gateway-side stores
+// can't be loaded, as saving them would overwrite what they hold.
+func NewServer(conf *config.Config, logger *slog.Logger) (*Server, error) {
 	s := new(Server)
 	s.r = mux.NewRouter()
 	s.logger = logger
 	s.port = conf.Port
 	s.handlers = map[string]IHandler{}
-
-	defer func(){
-		if r := recover(); r != nil {
-			s.logger.Error(r.(string))
-		}
-	}()
+	proxies, err := conf.TrustedProxyList()
+	if err != nil {
+		return nil, fmt.Errorf("can't parse trusted proxies: %w", err)
+	}
+	s.limiter = newRateLimiter(conf.RateLimit, conf.RateBurst, proxies)
+	s.timeout = new(requestTimeout)
+	s.timeout.Update(conf.RequestTimeout)
+	s.r.Use(s.limiter.Middleware, s.timeout.Middleware)
 
 	s.logger.Info("Register services...")
+
+	s.backends = map[string]*Backend{}
+	for name, addr := range conf.Backends() {
+		backend, err := NewBackend(name, addr, s.logger)
+		if err != nil {
+			return nil, err
+		}
+		s.backends[name] = backend
+	}
 	
 	s.RegisterHandler("profile", &ProfileHandler{
 		r: s.r.PathPrefix("/profile").Subrouter(),
 		logger: s.logger, 
-	}, MustConnect(conf.ProfileServiceAddr))
+	}, s.backends["profile"])
 	
-	s.RegisterHandler("feed", &FeedHandler{
+	fieldPresets, err := conf.ListingFieldPresets()
+	if err != nil {
+		return nil, fmt.Errorf("can't parse field presets: %w", err)
+	}
+	if conf.AccessTokenSecret == "" {
+		s.logger.Warn("ACCESS_TOKEN_SECRET is empty, user routes trust the user id they are given")
+	}
+	favorites, err := store.NewFavorites(conf.StoreDir)
+	if err != nil {
+		return nil, fmt.Errorf("can't load favorites: %w", err)
+	}
+	searches, err := store.NewSavedSearches(conf.StoreDir)
+	if err != nil {
+		return nil, fmt.Errorf("can't load saved searches: %w", err)
+	}
+	inbox, err := store.NewInbox(conf.StoreDir)
+	if err != nil {
+		return nil, fmt.Errorf("can't load inbox: %w", err)
+	}
+	watches, err := store.NewWatches(conf.StoreDir)
+	if err != nil {
+		return nil, fmt.Errorf("can't load watched listings: %w", err)
+	}
+	var events *notify.Stream
+	if notify.Listed(conf.NotifySinks, "sse") {
+		events = notify.NewStream()
+	}
+	notifier, err := notify.NewSinks(conf.NotifySinks, notify.Options{
+		Logger: s.logger,
+		Inbox: inbox,
+		WebhookURL: conf.NotifyWebhookURL,
+		WebhookSecret: conf.NotifyWebhookSecret,
+		Stream: events,
+		FilePath: conf.NotifyFile,
+	})
+	if err != nil {
+		s.logger.Error("can't set up notification sinks", slog.Any("error", err))
+	}
+
+	signer := blobs.NewSigner(conf.BlobSigningSecret, "/blobs")
+	blobStore, err := blobs.New(conf.BlobStore, blobs.Options{
+		Dir: conf.BlobDir,
+		MemoryMaxBytes: int64(conf.BlobMemoryMB) << 20,
+		Signer: signer,
+		S3: blobs.S3Options{
+			Endpoint: conf.S3Endpoint,
+			Bucket: conf.S3Bucket,
+			Region: conf.S3Region,
+			AccessKey: conf.S3AccessKey,
+			SecretKey: conf.S3SecretKey,
+			UseSSL: conf.S3UseSSL,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can't set up %s blob store: %w", conf.BlobStore, err)
+	}
+	if _, ok := blobStore.(*blobs.S3); !ok {
+		s.r.PathPrefix("/blobs/").Methods("GET").Handler(signer.Handler(blobStore))
+	}
+	if sweeper, ok := blobStore.(blobs.Sweeper); ok {
+		s.background = append(s.background, func(ctx context.Context) {
+			s.sweepEvery(ctx, "blobs", sweeper.Sweep)
+		})
+	}
+
+	feedHandler := &FeedHandler{
 		r: s.r.PathPrefix("/feed").Subrouter(),
 		logger: s.logger, 
-	}, MustConnect(conf.FeedServiceAddr))
+		scanLimit: conf.ScanLimit,
+		cursors: pagination.NewCodec(conf.CursorSecret),
+		fieldPresets: fieldPresets,
+		bulk: newBulkImporter(conf.BulkConcurrency, conf.BulkAsyncRows, conf.BulkMaxRows),
+		exportRows: conf.ExportMaxRows,
+		favorites: favorites,
+		tokens: auth.NewVerifier(conf.AccessTokenSecret),
+		searches: searches,
+		inbox: inbox,
+		notifier: notifier,
+		watches: watches,
+		events: events,
+		changes: newListingEvents(conf.ListingEventsBuffer),
+		predictions: model.NewPredictionServiceClient(s.backends["prediction"]),
+		valuation: domain.ValuationThresholds{
+			GoodDealPct: conf.ValuationGoodDeal,
+			OverpricedPct: conf.ValuationOverpriced,
+		},
+		valuationConcurrency: conf.ValuationConcurrency,
+		valuationBudget: conf.ValuationBudget,
+		blobs: blobStore,
+		exportLinkTTL: conf.ExportLinkTTL,
+	}
+	s.RegisterHandler("feed", feedHandler, s.backends["feed"])
+	if conf.SavedSearchInterval > 0 && notifier != nil {
+		s.background = append(s.background, func(ctx context.Context) {
+			feedHandler.watchSearches(ctx, conf.SavedSearchInterval)
+		})
+	}
+	if conf.WatchInterval > 0 && notifier != nil {
+		s.background = append(s.background, func(ctx context.Context) {
+			feedHandler.watchListings(ctx, conf.WatchInterval)
+		})
+	}
 
+	images := newImageProxy(conf.ImageProxyHostList(), conf.ImageCacheDir, conf.ImageCacheTTL, int64(conf.ImageCacheMaxMB)<<20, s.logger)
+	if images.enabled() && conf.ImageCacheDir != "" {
+		s.background = append(s.background, func(ctx context.Context) {
+			// the cache left by a previous run may be over its size already
+			if err := images.sweep(ctx); err != nil {
+				s.logger.Warn("can't sweep cached images", slog.Any("error", err))
+			}
+			s.sweepEvery(ctx, "cached images", images.sweep)
+		})
+	}
 	s.RegisterHandler("prediction", &PredictionHandler{
 		r: s.r.PathPrefix("/prediction").Subrouter(),
 		logger: s.logger, 
-	}, MustConnect(conf.PredictionServiceAddr))
+		session: sessionOptions{
+			debounce: conf.PredictionWsDebounce,
+			rate: conf.PredictionWsRate,
+			origins: conf.PredictionWsOriginList(),
+		},
+		batch: newBatchPredictor(conf.BatchConcurrency, conf.BatchAsyncItems, conf.BatchMaxItems),
+		graphs: blobStore,
+		graphTTL: conf.GraphTTL,
+		images: images,
+	}, s.backends["prediction"])
+
+	if conf.TranscodeEnabled {
+		if err := s.registerTranscoding(conf); err != nil {
+			return nil, err
+		}
+	}
+
+	if conf.RpcEnabled {
+		if err := s.registerRPC(conf); err != nil {
+			return nil, err
+		}
+	}
 
 	s.logger.Info("Handlers registration completed!")
 
-	return s
+	return s, nil
+}
+
+// sweepEvery runs sweep removing expired what every sweepInterval until ctx
+// is done.
+func (s *Server) sweepEvery(ctx context.Context, what string, sweep func(ctx context.Context) error) {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := sweep(ctx); err != nil {
+				s.logger.Warn("can't sweep expired "+what, slog.Any("error", err))
+			}
+		}
+	}
+}
+
+// registerTranscoding mounts generic JSON -> gRPC routes. Their subrouters are
+// created after the hand-written handlers' ones in NewServer, and mux tries
+// routes in the order they were added to the parent router, so hand-written
+// routes keep precedence on the same paths whatever order Run sets routes up in.
+func (s *Server) registerTranscoding(conf *config.Config) error {
+	rules, err := transcoding.ParseRules(conf.TranscodeRoutes)
+	if err != nil {
+		return fmt.Errorf("can't parse transcoding routes: %w", err)
+	}
+
+	services := map[string]string{
+		"profile": profile.ProfileService_ServiceDesc.ServiceName,
+		"feed": feed.FeedService_ServiceDesc.ServiceName,
+		"prediction": model.PredictionService_ServiceDesc.ServiceName,
+	}
+
+	for name, service := range services {
+		h := &TranscodingHandler{
+			r: s.r.PathPrefix("/" + name).Subrouter(),
+			logger: s.logger,
+			services: []string{service},
+		}
+		h.AddRules(rules)
+		s.RegisterHandler(name + "-transcoding", h, s.backends[name])
+	}
+	return nil
+}
+
+func (s *Server) registerRPC(conf *config.Config) error {
+	descriptorSets, err := conf.DescriptorSets()
+	if err != nil {
+		return fmt.Errorf("can't parse descriptor sets: %w", err)
+	}
+
+	allowlist := transcoding.ParseAllowlist(conf.RpcAllowlist)
+	r := s.r.PathPrefix("/rpc").Subrouter()
+
+	for name, backend := range s.backends {
+		s.RegisterHandler(name + "-rpc", &RPCHandler{
+			r: r,
+			logger: s.logger,
+			backend: name,
+			descriptorSet: descriptorSets[name],
+			allowlist: allowlist,
+		}, backend)
+	}
+	return nil
 }
 
-func MustConnect(addr string) *grpc.ClientConn {
+// Connect makes a client of the gRPC backend at addr, retrying with backoff.
+func Connect(addr string) (*grpc.ClientConn, error) {
 	var (
 		wait time.Duration = time.Second
 		err error
@@ -81,13 +309,13 @@ func MustConnect(addr string) *grpc.ClientConn {
 	}
 
 	if err != nil {
-		panic(fmt.Sprintf("can't connect to %s, error: %v", addr, err))
+		return nil, fmt.Errorf("can't connect to %s: %w", addr, err)
 	}
 	
-	return cc
+	return cc, nil
 }
 
-func (s *Server) RegisterHandler(name string, h IHandler, conn *grpc.ClientConn) {
+func (s *Server) RegisterHandler(name string, h IHandler, conn grpc.ClientConnInterface) {
 	if _, ok := s.handlers[name]; ok {
 		s.logger.Warn(fmt.Sprintf("attempt to recreate handler %s", name))
 		return
@@ -97,10 +325,27 @@ func (s *Server) RegisterHandler(name string, h IHandler, conn *grpc.ClientConn)
 	s.handlers[name] = h
 }
 
+// ApplyConfig updates settings which are safe to change without restart.
+func (s *Server) ApplyConfig(conf *config.Config) {
+	// reloaded config is validated, so proxies parse
+	proxies, _ := conf.TrustedProxyList()
+	s.limiter.Update(conf.RateLimit, conf.RateBurst, proxies)
+	s.timeout.Update(conf.RequestTimeout)
+
+	for name, addr := range conf.Backends() {
+		if err := s.backends[name].SetAddr(addr); err != nil {
+			s.logger.Error("can't switch backend address", slog.String("backend", name), slog.Any("error", err))
+		}
+	}
+}
+
 func (s *Server) Run() error {
 	for _, handler := range s.handlers {
 		handler.setupRoutes()
 	}
+	for _, job := range s.background {
+		go job(context.Background())
+	}
 
 	s.logger.Info("Running API Gateway server...", slog.String("port", s.port))
 	return http.ListenAndServe(":" + s.port, s.r)
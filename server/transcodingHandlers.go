@@ -0,0 +1,100 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc"
+
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/transcoding"
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/utils"
+)
+
+type TranscodingHandler struct {
+	r        *mux.Router
+	logger   *slog.Logger
+	conn     grpc.ClientConnInterface
+	services []string
+	rules    []*transcoding.Rule
+}
+
+func (h *TranscodingHandler) setupgRPC(conn grpc.ClientConnInterface) {
+	h.conn = conn
+}
+
+func (h *TranscodingHandler) setupRoutes() {
+	for _, name := range h.services {
+		sd, err := transcoding.FindService(name)
+		if err != nil {
+			h.logger.Error("can't transcode service", slog.Any("error", err))
+			continue
+		}
+		h.rules = append(h.rules, transcoding.FromAnnotations(sd)...)
+	}
+
+	registered := map[string]bool{}
+	for _, rule := range h.rules {
+		if rule.RPC.IsStreamingClient() || rule.RPC.IsStreamingServer() {
+			h.logger.Warn("streaming methods can't be transcoded", slog.String("rule", rule.String()))
+			continue
+		}
+
+		key := rule.HttpMethod + " " + rule.Pattern
+		if registered[key] {
+			h.logger.Warn("duplicate transcoding route skipped", slog.String("rule", rule.String()))
+			continue
+		}
+		registered[key] = true
+
+		h.r.HandleFunc(rule.MuxPattern(), transcode(h.logger, h.conn, rule)).Methods(rule.HttpMethod)
+		h.logger.Info("Transcoding route registered", slog.String("rule", rule.String()))
+	}
+}
+
+// AddRules keeps explicit rules which belong to the handler backend services.
+func (h *TranscodingHandler) AddRules(rules []*transcoding.Rule) {
+	for _, rule := range rules {
+		for _, name := range h.services {
+			if string(rule.RPC.Parent().FullName()) == name {
+				h.rules = append(h.rules, rule)
+			}
+		}
+	}
+}
+
+func transcode(logger *slog.Logger, conn grpc.ClientConnInterface, rule *transcoding.Rule) http.HandlerFunc {
+	op := fmt.Sprintf("%s failed: ", rule.RPC.Name())
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.With(slog.String("op", "transcode"), slog.String("method", rule.FullMethod()))
+
+		req, err := transcoding.BuildRequest(rule, r, mux.Vars(r))
+		var maxBytes *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytes):
+			http.Error(w, fmt.Sprintf("body is too large, at most %d bytes are allowed", maxBytes.Limit), http.StatusRequestEntityTooLarge)
+			return
+		case err != nil:
+			http.Error(w, "bad request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		log.Info("→ gRPC "+string(rule.RPC.Name()), slog.Any("req", req))
+		resp, err := transcoding.Invoke(r, conn, rule, req)
+		if err != nil {
+			utils.HandleResponseErr(w, logger, op, err)
+			return
+		}
+
+		bytes, err := transcoding.RenderResponse(rule, resp)
+		if err != nil {
+			http.Error(w, "Can't render JSON from object: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(bytes)
+	}
+}
@@ -0,0 +1,94 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+type Favorite struct {
+	ListingId string    `json:"listing_id"`
+	AddedAt   time.Time `json:"added_at"`
+}
+
+// Favorites keeps users' favorite listings on the gateway, as feed service
+// can only add them.
+type Favorites interface {
+	Add(ctx context.Context, userId, listingId string) error
+	Remove(ctx context.Context, userId, listingId string) (bool, error)
+	// List returns user favorites, the latest added first.
+	List(ctx context.Context, userId string) ([]Favorite, error)
+	Contains(ctx context.Context, userId string, listingIds []string) (map[string]bool, error)
+}
+
+type fileFavorites struct {
+	mu    sync.RWMutex
+	file  jsonFile
+	users map[string]map[string]time.Time
+}
+
+// NewFavorites returns Favorites kept in dir, or in memory if dir is empty.
+func NewFavorites(dir string) (Favorites, error) {
+	f := &fileFavorites{
+		file:  newJSONFile(dir, "favorites.json"),
+		users: map[string]map[string]time.Time{},
+	}
+	return f, f.file.load(&f.users)
+}
+
+func (f *fileFavorites) Add(_ context.Context, userId, listingId string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.users[userId] == nil {
+		f.users[userId] = map[string]time.Time{}
+	}
+	if _, ok := f.users[userId][listingId]; ok {
+		return nil
+	}
+	f.users[userId][listingId] = time.Now().UTC()
+	return f.file.save(f.users)
+}
+
+func (f *fileFavorites) Remove(_ context.Context, userId, listingId string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.users[userId][listingId]; !ok {
+		return false, nil
+	}
+	delete(f.users[userId], listingId)
+	if len(f.users[userId]) == 0 {
+		delete(f.users, userId)
+	}
+	return true, f.file.save(f.users)
+}
+
+func (f *fileFavorites) List(_ context.Context, userId string) ([]Favorite, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	list := make([]Favorite, 0, len(f.users[userId]))
+	for id, added := range f.users[userId] {
+		list = append(list, Favorite{ListingId: id, AddedAt: added})
+	}
+	slices.SortFunc(list, func(a, b Favorite) int {
+		return cmp.Or(b.AddedAt.Compare(a.AddedAt), cmp.Compare(a.ListingId, b.ListingId))
+	})
+	return list, nil
+}
+
+func (f *fileFavorites) Contains(_ context.Context, userId string, listingIds []string) (map[string]bool, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	found := map[string]bool{}
+	for _, id := range listingIds {
+		if _, ok := f.users[userId][id]; ok {
+			found[id] = true
+		}
+	}
+	return found, nil
+}
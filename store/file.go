@@ -0,0 +1,56 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// jsonFile persists a value as JSON. Empty path keeps data in memory only.
+type jsonFile struct {
+	path string
+}
+
+func newJSONFile(dir, name string) jsonFile {
+	if dir == "" {
+		return jsonFile{}
+	}
+	return jsonFile{path: filepath.Join(dir, name)}
+}
+
+// load reads the file into v, a missing file leaves v as is.
+func (f jsonFile) load(v any) error {
+	if f.path == "" {
+		return nil
+	}
+	raw, err := os.ReadFile(f.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// save writes v through a temporary file, so readers never see a partial one.
+func (f jsonFile) save(v any) error {
+	if f.path == "" {
+		return nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+		return err
+	}
+	tmp := f.path + ".tmp"
+	if err = os.WriteFile(tmp, raw, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.path)
+}
@@ -0,0 +1,74 @@
+package store
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/domain"
+)
+
+// inboxSize is how many latest notifications are kept per user.
+const inboxSize = 200
+
+// Inbox keeps notifications shown to users in the app.
+type Inbox interface {
+	Add(ctx context.Context, n *domain.Notification) error
+	// List returns user notifications, the latest first.
+	List(ctx context.Context, userId string) ([]domain.Notification, error)
+	Delete(ctx context.Context, userId, notificationId string) (bool, error)
+}
+
+type fileInbox struct {
+	mu    sync.RWMutex
+	file  jsonFile
+	users map[string][]domain.Notification
+}
+
+// NewInbox returns Inbox kept in dir, or in memory if dir is empty.
+func NewInbox(dir string) (Inbox, error) {
+	i := &fileInbox{
+		file:  newJSONFile(dir, "inbox.json"),
+		users: map[string][]domain.Notification{},
+	}
+	return i, i.file.load(&i.users)
+}
+
+func (i *fileInbox) Add(_ context.Context, n *domain.Notification) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	list := append(i.users[n.UserId], *n)
+	if len(list) > inboxSize {
+		list = slices.Delete(list, 0, len(list)-inboxSize)
+	}
+	i.users[n.UserId] = list
+	return i.file.save(i.users)
+}
+
+func (i *fileInbox) List(_ context.Context, userId string) ([]domain.Notification, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	list := make([]domain.Notification, len(i.users[userId]))
+	copy(list, i.users[userId])
+	slices.Reverse(list)
+	return list, nil
+}
+
+func (i *fileInbox) Delete(_ context.Context, userId, notificationId string) (bool, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	idx := slices.IndexFunc(i.users[userId], func(n domain.Notification) bool {
+		return n.NotificationId == notificationId
+	})
+	if idx < 0 {
+		return false, nil
+	}
+	i.users[userId] = slices.Delete(i.users[userId], idx, idx+1)
+	if len(i.users[userId]) == 0 {
+		delete(i.users, userId)
+	}
+	return true, i.file.save(i.users)
+}
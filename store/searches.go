@@ -0,0 +1,151 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/domain"
+)
+
+var ErrNotFound = errors.New("not found")
+
+// SavedSearches keeps users' saved searches together with listing ids seen
+// by the last run of each.
+type SavedSearches interface {
+	// Save creates or replaces the search, resetting its LastRunAt when what
+	// it matches changed.
+	Save(ctx context.Context, s *domain.SavedSearch) error
+	Get(ctx context.Context, userId, searchId string) (*domain.SavedSearch, error)
+	List(ctx context.Context, userId string) ([]domain.SavedSearch, error)
+	Delete(ctx context.Context, userId, searchId string) (bool, error)
+	// All returns searches of every user, for the worker re-running them.
+	All(ctx context.Context) ([]domain.SavedSearch, error)
+	// Seen returns listing ids matched by the last run, ran is false if there
+	// was none.
+	Seen(ctx context.Context, searchId string) (ids []string, ran bool, err error)
+	SetSeen(ctx context.Context, searchId string, ids []string, at time.Time) error
+}
+
+type savedSearch struct {
+	domain.SavedSearch
+	Seen []string `json:"seen,omitempty"`
+}
+
+type fileSearches struct {
+	mu       sync.RWMutex
+	file     jsonFile
+	searches map[string]*savedSearch
+}
+
+// NewSavedSearches returns SavedSearches kept in dir, or in memory if dir is empty.
+func NewSavedSearches(dir string) (SavedSearches, error) {
+	s := &fileSearches{
+		file:     newJSONFile(dir, "searches.json"),
+		searches: map[string]*savedSearch{},
+	}
+	return s, s.file.load(&s.searches)
+}
+
+// Save keeps seen ids of the old search unless its query changed.
+func (s *fileSearches) Save(_ context.Context, search *domain.SavedSearch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var seen []string
+	if old, ok := s.searches[search.SearchId]; ok {
+		if old.UserId != search.UserId {
+			return ErrNotFound
+		}
+		if sameQuery(&old.SavedSearch, search) {
+			seen = old.Seen
+		} else {
+			search.LastRunAt = nil
+		}
+	}
+	stored := &savedSearch{SavedSearch: *search, Seen: seen}
+	s.searches[search.SearchId] = stored
+	return s.file.save(s.searches)
+}
+
+func sameQuery(a, b *domain.SavedSearch) bool {
+	return a.Query == b.Query && a.Sort == b.Sort && reflect.DeepEqual(a.Filter, b.Filter)
+}
+
+func (s *fileSearches) Get(_ context.Context, userId, searchId string) (*domain.SavedSearch, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stored, ok := s.searches[searchId]
+	if !ok || stored.UserId != userId {
+		return nil, ErrNotFound
+	}
+	search := stored.SavedSearch
+	return &search, nil
+}
+
+func (s *fileSearches) List(_ context.Context, userId string) ([]domain.SavedSearch, error) {
+	return s.filter(func(search *savedSearch) bool { return search.UserId == userId }), nil
+}
+
+func (s *fileSearches) All(_ context.Context) ([]domain.SavedSearch, error) {
+	return s.filter(func(*savedSearch) bool { return true }), nil
+}
+
+func (s *fileSearches) filter(keep func(search *savedSearch) bool) []domain.SavedSearch {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := []domain.SavedSearch{}
+	for _, search := range s.searches {
+		if keep(search) {
+			list = append(list, search.SavedSearch)
+		}
+	}
+	slices.SortFunc(list, func(a, b domain.SavedSearch) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return list
+}
+
+func (s *fileSearches) Delete(_ context.Context, userId, searchId string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if stored, ok := s.searches[searchId]; !ok || stored.UserId != userId {
+		return false, nil
+	}
+	delete(s.searches, searchId)
+	return true, s.file.save(s.searches)
+}
+
+func (s *fileSearches) Seen(_ context.Context, searchId string) ([]string, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stored, ok := s.searches[searchId]
+	if !ok {
+		return nil, false, ErrNotFound
+	}
+	if stored.LastRunAt == nil {
+		return nil, false, nil
+	}
+	return slices.Clone(stored.Seen), true, nil
+}
+
+func (s *fileSearches) SetSeen(_ context.Context, searchId string, ids []string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.searches[searchId]
+	if !ok {
+		// deleted while it was running
+		return nil
+	}
+	stored.Seen = ids
+	stored.LastRunAt = &at
+	return s.file.save(s.searches)
+}
@@ -0,0 +1,111 @@
+package store
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/nikita-itmo-gh-acc/car_estimator_api_gateway/domain"
+)
+
+// Watches keeps listings users watch for price and status changes, with
+// the snapshot of each the changes are detected against.
+type Watches interface {
+	// Add subscribes the user, snapshot is kept only for listings not watched yet.
+	Add(ctx context.Context, userId string, snapshot domain.ListingSnapshot) (domain.ListingSnapshot, error)
+	Remove(ctx context.Context, userId, listingId string) (bool, error)
+	All(ctx context.Context) ([]domain.WatchedListing, error)
+	Update(ctx context.Context, snapshot domain.ListingSnapshot) error
+	// Drop stops watching the listing for every user.
+	Drop(ctx context.Context, listingId string) error
+}
+
+type watchedListing struct {
+	Snapshot domain.ListingSnapshot `json:"snapshot"`
+	UserIds  []string               `json:"user_ids"`
+}
+
+type fileWatches struct {
+	mu       sync.RWMutex
+	file     jsonFile
+	listings map[string]*watchedListing
+}
+
+// NewWatches returns Watches kept in dir, or in memory if dir is empty.
+func NewWatches(dir string) (Watches, error) {
+	w := &fileWatches{
+		file:     newJSONFile(dir, "watches.json"),
+		listings: map[string]*watchedListing{},
+	}
+	return w, w.file.load(&w.listings)
+}
+
+func (w *fileWatches) Add(_ context.Context, userId string, snapshot domain.ListingSnapshot) (domain.ListingSnapshot, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	watched, ok := w.listings[snapshot.ListingId]
+	if !ok {
+		watched = &watchedListing{Snapshot: snapshot}
+		w.listings[snapshot.ListingId] = watched
+	}
+	if slices.Contains(watched.UserIds, userId) {
+		return watched.Snapshot, nil
+	}
+	watched.UserIds = append(watched.UserIds, userId)
+	return watched.Snapshot, w.file.save(w.listings)
+}
+
+func (w *fileWatches) Remove(_ context.Context, userId, listingId string) (bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	watched, ok := w.listings[listingId]
+	if !ok {
+		return false, nil
+	}
+	idx := slices.Index(watched.UserIds, userId)
+	if idx < 0 {
+		return false, nil
+	}
+	watched.UserIds = slices.Delete(watched.UserIds, idx, idx+1)
+	if len(watched.UserIds) == 0 {
+		delete(w.listings, listingId)
+	}
+	return true, w.file.save(w.listings)
+}
+
+func (w *fileWatches) All(_ context.Context) ([]domain.WatchedListing, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	list := make([]domain.WatchedListing, 0, len(w.listings))
+	for _, watched := range w.listings {
+		list = append(list, domain.WatchedListing{
+			ListingSnapshot: watched.Snapshot,
+			UserIds:         slices.Clone(watched.UserIds),
+		})
+	}
+	return list, nil
+}
+
+func (w *fileWatches) Update(_ context.Context, snapshot domain.ListingSnapshot) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	watched, ok := w.listings[snapshot.ListingId]
+	if !ok {
+		// unwatched while it was checked
+		return nil
+	}
+	watched.Snapshot = snapshot
+	return w.file.save(w.listings)
+}
+
+func (w *fileWatches) Drop(_ context.Context, listingId string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	delete(w.listings, listingId)
+	return w.file.save(w.listings)
+}
@@ -0,0 +1,227 @@
+package transcoding
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"google.golang.org/grpc"
+	refl "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Discover asks backend for its services through gRPC server reflection.
+func Discover(ctx context.Context, conn grpc.ClientConnInterface) ([]protoreflect.ServiceDescriptor, error) {
+	stream, err := refl.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't open reflection stream: %v", err)
+	}
+	defer stream.CloseSend()
+
+	ask := func(req *refl.ServerReflectionRequest) (*refl.ServerReflectionResponse, error) {
+		if err := stream.Send(req); err != nil {
+			return nil, err
+		}
+		resp, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+		if e := resp.GetErrorResponse(); e != nil {
+			return nil, fmt.Errorf("reflection error %d: %s", e.GetErrorCode(), e.GetErrorMessage())
+		}
+		return resp, nil
+	}
+
+	resp, err := ask(&refl.ServerReflectionRequest{
+		MessageRequest: &refl.ServerReflectionRequest_ListServices{},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can't list services: %v", err)
+	}
+
+	set := &descriptorpb.FileDescriptorSet{}
+	var names []string
+	for _, service := range resp.GetListServicesResponse().GetService() {
+		if strings.HasPrefix(service.GetName(), "grpc.reflection.") {
+			continue
+		}
+		names = append(names, service.GetName())
+
+		resp, err := ask(&refl.ServerReflectionRequest{
+			MessageRequest: &refl.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: service.GetName()},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("can't get descriptor of %s: %v", service.GetName(), err)
+		}
+
+		for _, raw := range resp.GetFileDescriptorResponse().GetFileDescriptorProto() {
+			fdp := &descriptorpb.FileDescriptorProto{}
+			if err = proto.Unmarshal(raw, fdp); err != nil {
+				return nil, fmt.Errorf("bad descriptor of %s: %v", service.GetName(), err)
+			}
+			set.File = append(set.File, fdp)
+		}
+	}
+
+	files, err := buildFiles(set)
+	if err != nil {
+		return nil, err
+	}
+	return findServices(files, names)
+}
+
+// LoadDescriptorSet reads services from a FileDescriptorSet produced by
+// `protoc --include_imports --descriptor_set_out`.
+func LoadDescriptorSet(path string) ([]protoreflect.ServiceDescriptor, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't read descriptor set: %v", err)
+	}
+
+	set := &descriptorpb.FileDescriptorSet{}
+	if err = proto.Unmarshal(raw, set); err != nil {
+		return nil, fmt.Errorf("can't unpack descriptor set: %v", err)
+	}
+
+	files, err := buildFiles(set)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, fdp := range set.GetFile() {
+		for _, sd := range fdp.GetService() {
+			name := sd.GetName()
+			if fdp.GetPackage() != "" {
+				name = fdp.GetPackage() + "." + name
+			}
+			names = append(names, name)
+		}
+	}
+	return findServices(files, names)
+}
+
+// buildFiles links descriptors in dependency order. Imports missing from the
+// set (well-known types, google.api annotations) are taken from linked-in ones.
+func buildFiles(set *descriptorpb.FileDescriptorSet) (*protoregistry.Files, error) {
+	pending := map[string]*descriptorpb.FileDescriptorProto{}
+	for _, fdp := range set.GetFile() {
+		pending[fdp.GetName()] = fdp
+	}
+
+	files := new(protoregistry.Files)
+	var register func(name string) error
+	register = func(name string) error {
+		if _, err := files.FindFileByPath(name); err == nil {
+			return nil
+		}
+
+		fdp, ok := pending[name]
+		if !ok {
+			fd, err := protoregistry.GlobalFiles.FindFileByPath(name)
+			if err != nil {
+				return fmt.Errorf("missing dependency %s", name)
+			}
+			return files.RegisterFile(fd)
+		}
+		delete(pending, name)
+
+		for _, dep := range fdp.GetDependency() {
+			if err := register(dep); err != nil {
+				return err
+			}
+		}
+
+		fd, err := protodesc.NewFile(fdp, files)
+		if err != nil {
+			return fmt.Errorf("bad descriptor %s: %v", name, err)
+		}
+		return files.RegisterFile(fd)
+	}
+
+	for _, fdp := range set.GetFile() {
+		if err := register(fdp.GetName()); err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+func findServices(files *protoregistry.Files, names []string) ([]protoreflect.ServiceDescriptor, error) {
+	var services []protoreflect.ServiceDescriptor
+	seen := map[string]bool{}
+
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		d, err := files.FindDescriptorByName(protoreflect.FullName(name))
+		if err != nil {
+			return nil, fmt.Errorf("unknown service %s: %v", name, err)
+		}
+		sd, ok := d.(protoreflect.ServiceDescriptor)
+		if !ok {
+			return nil, fmt.Errorf("%s is not a service", name)
+		}
+		services = append(services, sd)
+	}
+
+	return services, nil
+}
+
+// Allowlist matches "package.Service/Method" names against entries which can
+// end with "/*" to allow every method of a service.
+type Allowlist []string
+
+func ParseAllowlist(raw string) Allowlist {
+	var list Allowlist
+	for _, entry := range strings.Split(raw, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			list = append(list, strings.TrimPrefix(entry, "/"))
+		}
+	}
+	return list
+}
+
+func (list Allowlist) Allows(md protoreflect.MethodDescriptor) bool {
+	service := string(md.Parent().FullName())
+	method := service + "/" + string(md.Name())
+
+	for _, entry := range list {
+		if entry == method || entry == service+"/*" || entry == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// RPCRules exposes allowlisted unary methods as POST /{service}/{method} with JSON body.
+func RPCRules(services []protoreflect.ServiceDescriptor, allowlist Allowlist) []*Rule {
+	var rules []*Rule
+
+	for _, sd := range services {
+		methods := sd.Methods()
+		for i := 0; i < methods.Len(); i++ {
+			md := methods.Get(i)
+			if md.IsStreamingClient() || md.IsStreamingServer() || !allowlist.Allows(md) {
+				continue
+			}
+			rules = append(rules, &Rule{
+				HttpMethod: "POST",
+				Pattern:    fmt.Sprintf("/%s/%s", sd.FullName(), md.Name()),
+				RPC:        md,
+				Body:       "*",
+			})
+		}
+	}
+
+	return rules
+}
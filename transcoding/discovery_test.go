@@ -0,0 +1,125 @@
+package transcoding
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	feed "github.com/nikita-itmo-gh-acc/car_estimator_api_contracts/gen/feed_v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// writeDescriptorSet writes feed.proto as a descriptor set without its
+// imports, like protoc does without --include_imports.
+func writeDescriptorSet(t *testing.T) string {
+	t.Helper()
+	set := &descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{protodesc.ToFileDescriptorProto(feed.File_feed_proto)},
+	}
+	raw, err := proto.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "feed.pb")
+	if err = os.WriteFile(path, raw, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func rulePatterns(rules []*Rule) []string {
+	patterns := make([]string, len(rules))
+	for i, r := range rules {
+		patterns[i] = r.HttpMethod + " " + r.Pattern
+	}
+	return patterns
+}
+
+func TestLoadDescriptorSet(t *testing.T) {
+	services, err := LoadDescriptorSet(writeDescriptorSet(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 1 || services[0].FullName() != "feed.FeedService" {
+		t.Fatalf("services = %v, want feed.FeedService", services)
+	}
+
+	rules := RPCRules(services, ParseAllowlist("feed.FeedService/GetListing, /feed.FeedService/ListListings"))
+	want := []string{"POST /feed.FeedService/ListListings", "POST /feed.FeedService/GetListing"}
+	if got := rulePatterns(rules); !slices.Equal(got, want) {
+		t.Errorf("rules = %v, want %v", got, want)
+	}
+	if rules[0].RPC.Input().FullName() != "feed.ListListingsRequest" {
+		t.Errorf("ListListings input = %s", rules[0].RPC.Input().FullName())
+	}
+}
+
+func TestLoadDescriptorSetFails(t *testing.T) {
+	bad := filepath.Join(t.TempDir(), "bad.pb")
+	os.WriteFile(bad, []byte("not a descriptor set"), 0o644)
+
+	for _, path := range []string{filepath.Join(t.TempDir(), "missing.pb"), bad} {
+		if _, err := LoadDescriptorSet(path); err == nil {
+			t.Errorf("LoadDescriptorSet(%s) succeeded", filepath.Base(path))
+		}
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	feed.RegisterFeedServiceServer(srv, feed.UnimplementedFeedServiceServer{})
+	reflection.Register(srv)
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	services, err := Discover(context.Background(), conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 1 || services[0].FullName() != "feed.FeedService" {
+		t.Fatalf("services = %v, want feed.FeedService only", services)
+	}
+	if n := len(RPCRules(services, ParseAllowlist("feed.FeedService/*"))); n != services[0].Methods().Len() {
+		t.Errorf("service wildcard allows %d of %d methods", n, services[0].Methods().Len())
+	}
+}
+
+func TestAllowlist(t *testing.T) {
+	method := feed.File_feed_proto.Services().ByName("FeedService").Methods().ByName("DeleteListing")
+
+	tests := []struct {
+		list string
+		want bool
+	}{
+		{"", false},
+		{"feed.FeedService/GetListing", false},
+		{"feed.FeedService/DeleteListing", true},
+		{"/feed.FeedService/DeleteListing", true},
+		{"feed.FeedService/*", true},
+		{"profile.ProfileService/*", false},
+		{"*", true},
+	}
+	for _, tt := range tests {
+		if got := ParseAllowlist(tt.list).Allows(method); got != tt.want {
+			t.Errorf("%q allows %s = %v, want %v", tt.list, method.FullName(), got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package transcoding
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+type Rule struct {
+	HttpMethod   string
+	Pattern      string
+	RPC          protoreflect.MethodDescriptor
+	Body         string
+	ResponseBody string
+}
+
+var templateVar = regexp.MustCompile(`\{([^}=]+)(=([^}]*))?\}`)
+
+// MuxPattern converts google.api.http path template into gorilla/mux route template.
+func (rule *Rule) MuxPattern() string {
+	return templateVar.ReplaceAllStringFunc(rule.Pattern, func(v string) string {
+		m := templateVar.FindStringSubmatch(v)
+		if m[3] == "" {
+			return "{" + m[1] + "}"
+		}
+
+		segments := strings.Split(m[3], "/")
+		for i, s := range segments {
+			switch s {
+			case "*":
+				segments[i] = "[^/]+"
+			case "**":
+				segments[i] = ".+"
+			default:
+				segments[i] = regexp.QuoteMeta(s)
+			}
+		}
+		return "{" + m[1] + ":" + strings.Join(segments, "/") + "}"
+	})
+}
+
+func (rule *Rule) FullMethod() string {
+	return fmt.Sprintf("/%s/%s", rule.RPC.Parent().FullName(), rule.RPC.Name())
+}
+
+func (rule *Rule) String() string {
+	return fmt.Sprintf("%s %s -> %s", rule.HttpMethod, rule.Pattern, rule.FullMethod())
+}
+
+func FindMethod(name string) (protoreflect.MethodDescriptor, error) {
+	service, method, ok := strings.Cut(strings.TrimPrefix(name, "/"), "/")
+	if !ok {
+		return nil, fmt.Errorf("method %q must look like package.Service/Method", name)
+	}
+
+	sd, err := FindService(service)
+	if err != nil {
+		return nil, err
+	}
+
+	md := sd.Methods().ByName(protoreflect.Name(method))
+	if md == nil {
+		return nil, fmt.Errorf("service %s has no method %s", service, method)
+	}
+	return md, nil
+}
+
+func FindService(name string) (protoreflect.ServiceDescriptor, error) {
+	d, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, fmt.Errorf("unknown service %s: %v", name, err)
+	}
+
+	sd, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a service", name)
+	}
+	return sd, nil
+}
+
+// FromAnnotations collects rules from google.api.http options of every service method.
+func FromAnnotations(sd protoreflect.ServiceDescriptor) []*Rule {
+	var rules []*Rule
+
+	methods := sd.Methods()
+	for i := 0; i < methods.Len(); i++ {
+		md := methods.Get(i)
+		if md.Options() == nil || !proto.HasExtension(md.Options(), annotations.E_Http) {
+			continue
+		}
+
+		httpRule := proto.GetExtension(md.Options(), annotations.E_Http).(*annotations.HttpRule)
+		if rule := fromHttpRule(md, httpRule); rule != nil {
+			rules = append(rules, rule)
+		}
+		for _, binding := range httpRule.GetAdditionalBindings() {
+			if rule := fromHttpRule(md, binding); rule != nil {
+				rules = append(rules, rule)
+			}
+		}
+	}
+
+	return rules
+}
+
+func fromHttpRule(md protoreflect.MethodDescriptor, httpRule *annotations.HttpRule) *Rule {
+	rule := &Rule{
+		RPC:          md,
+		Body:         httpRule.GetBody(),
+		ResponseBody: httpRule.GetResponseBody(),
+	}
+
+	switch p := httpRule.GetPattern().(type) {
+	case *annotations.HttpRule_Get:
+		rule.HttpMethod, rule.Pattern = "GET", p.Get
+	case *annotations.HttpRule_Post:
+		rule.HttpMethod, rule.Pattern = "POST", p.Post
+	case *annotations.HttpRule_Put:
+		rule.HttpMethod, rule.Pattern = "PUT", p.Put
+	case *annotations.HttpRule_Delete:
+		rule.HttpMethod, rule.Pattern = "DELETE", p.Delete
+	case *annotations.HttpRule_Patch:
+		rule.HttpMethod, rule.Pattern = "PATCH", p.Patch
+	case *annotations.HttpRule_Custom:
+		rule.HttpMethod, rule.Pattern = p.Custom.GetKind(), p.Custom.GetPath()
+	default:
+		return nil
+	}
+
+	return rule
+}
+
+// ParseRules reads explicit route mappings separated by ';', each one in form
+// "METHOD /path/{field} package.Service/Method [body=field] [response_body=field]".
+func ParseRules(raw string) ([]*Rule, error) {
+	var rules []*Rule
+
+	for _, entry := range strings.Split(raw, ";") {
+		parts := strings.Fields(entry)
+		if len(parts) == 0 {
+			continue
+		}
+		if len(parts) < 3 {
+			return nil, fmt.Errorf("bad transcoding rule %q: expected METHOD PATH SERVICE/METHOD", entry)
+		}
+
+		md, err := FindMethod(parts[2])
+		if err != nil {
+			return nil, fmt.Errorf("bad transcoding rule %q: %v", entry, err)
+		}
+
+		rule := &Rule{
+			HttpMethod: strings.ToUpper(parts[0]),
+			Pattern:    parts[1],
+			RPC:        md,
+		}
+
+		for _, opt := range parts[3:] {
+			key, value, _ := strings.Cut(opt, "=")
+			switch key {
+			case "body":
+				rule.Body = value
+			case "response_body":
+				rule.ResponseBody = value
+			default:
+				return nil, fmt.Errorf("bad transcoding rule %q: unknown option %s", entry, key)
+			}
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
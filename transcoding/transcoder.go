@@ -0,0 +1,199 @@
+package transcoding
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// MaxBodyBytes limits request bodies read by BuildRequest.
+const MaxBodyBytes = 4 << 20
+
+var marshaler = protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}
+
+// BuildRequest fills rule input message from body, path variables and query
+// params. Bodies over MaxBodyBytes fail with *http.MaxBytesError.
+func BuildRequest(rule *Rule, r *http.Request, pathVars map[string]string) (proto.Message, error) {
+	msg := dynamicpb.NewMessage(rule.RPC.Input())
+
+	if rule.Body != "" {
+		raw, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, MaxBodyBytes))
+		if err != nil {
+			return nil, fmt.Errorf("can't read body: %w", err)
+		}
+		if len(raw) > 0 {
+			if err = unmarshalBody(msg, rule.Body, raw); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for path, value := range pathVars {
+		if err := setField(msg, path, []string{value}); err != nil {
+			return nil, err
+		}
+	}
+
+	// query parameters are ignored when the whole message is bound to the body
+	if rule.Body == "*" {
+		return msg, nil
+	}
+
+	for path, values := range r.URL.Query() {
+		if _, ok := pathVars[path]; ok {
+			continue
+		}
+		if rule.Body != "" && (path == rule.Body || strings.HasPrefix(path, rule.Body+".")) {
+			continue
+		}
+		if err := setField(msg, path, values); err != nil {
+			return nil, err
+		}
+	}
+
+	return msg, nil
+}
+
+func unmarshalBody(msg *dynamicpb.Message, body string, raw []byte) error {
+	if body == "*" {
+		if err := protojson.Unmarshal(raw, msg); err != nil {
+			return fmt.Errorf("can't unpack json: %v", err)
+		}
+		return nil
+	}
+
+	fd := msg.Descriptor().Fields().ByName(protoreflect.Name(body))
+	if fd == nil || fd.Kind() != protoreflect.MessageKind || fd.IsList() || fd.IsMap() {
+		return fmt.Errorf("body field %s must be a singular message field", body)
+	}
+
+	if err := protojson.Unmarshal(raw, msg.Mutable(fd).Message().Interface()); err != nil {
+		return fmt.Errorf("can't unpack json: %v", err)
+	}
+	return nil
+}
+
+func setField(msg protoreflect.Message, path string, values []string) error {
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		fd := msg.Descriptor().Fields().ByName(protoreflect.Name(name))
+		if fd == nil {
+			fd = msg.Descriptor().Fields().ByJSONName(name)
+		}
+		if fd == nil {
+			return fmt.Errorf("unknown field %s", path)
+		}
+
+		if i < len(names)-1 {
+			if fd.Kind() != protoreflect.MessageKind || fd.IsList() || fd.IsMap() {
+				return fmt.Errorf("field %s is not a message", strings.Join(names[:i+1], "."))
+			}
+			msg = msg.Mutable(fd).Message()
+			continue
+		}
+
+		if fd.IsMap() {
+			return fmt.Errorf("map field %s can't be bound to a parameter", path)
+		}
+
+		if fd.IsList() {
+			list := msg.Mutable(fd).List()
+			for _, value := range values {
+				v, err := parseScalar(fd, value)
+				if err != nil {
+					return fmt.Errorf("field %s: %v", path, err)
+				}
+				list.Append(v)
+			}
+			return nil
+		}
+
+		v, err := parseScalar(fd, values[len(values)-1])
+		if err != nil {
+			return fmt.Errorf("field %s: %v", path, err)
+		}
+		msg.Set(fd, v)
+	}
+
+	return nil
+}
+
+func parseScalar(fd protoreflect.FieldDescriptor, value string) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(value), nil
+	case protoreflect.BoolKind:
+		v, err := strconv.ParseBool(value)
+		return protoreflect.ValueOfBool(v), err
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		v, err := strconv.ParseInt(value, 10, 32)
+		return protoreflect.ValueOfInt32(int32(v)), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		v, err := strconv.ParseInt(value, 10, 64)
+		return protoreflect.ValueOfInt64(v), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		v, err := strconv.ParseUint(value, 10, 32)
+		return protoreflect.ValueOfUint32(uint32(v)), err
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		v, err := strconv.ParseUint(value, 10, 64)
+		return protoreflect.ValueOfUint64(v), err
+	case protoreflect.FloatKind:
+		v, err := strconv.ParseFloat(value, 32)
+		return protoreflect.ValueOfFloat32(float32(v)), err
+	case protoreflect.DoubleKind:
+		v, err := strconv.ParseFloat(value, 64)
+		return protoreflect.ValueOfFloat64(v), err
+	case protoreflect.BytesKind:
+		v, err := base64.StdEncoding.DecodeString(value)
+		return protoreflect.ValueOfBytes(v), err
+	case protoreflect.EnumKind:
+		values := fd.Enum().Values()
+		if ev := values.ByName(protoreflect.Name(value)); ev != nil {
+			return protoreflect.ValueOfEnum(ev.Number()), nil
+		}
+		if ev := values.ByName(protoreflect.Name(strings.ToUpper(value))); ev != nil {
+			return protoreflect.ValueOfEnum(ev.Number()), nil
+		}
+		n, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || values.ByNumber(protoreflect.EnumNumber(n)) == nil {
+			return protoreflect.Value{}, fmt.Errorf("unknown enum value %s", value)
+		}
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(n)), nil
+	}
+
+	return protoreflect.Value{}, fmt.Errorf("unsupported field kind %s", fd.Kind())
+}
+
+func Invoke(r *http.Request, conn grpc.ClientConnInterface, rule *Rule, req proto.Message) (proto.Message, error) {
+	resp := dynamicpb.NewMessage(rule.RPC.Output())
+	if err := conn.Invoke(r.Context(), rule.FullMethod(), req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// RenderResponse marshals response message (or its response_body field) to JSON.
+func RenderResponse(rule *Rule, resp proto.Message) ([]byte, error) {
+	if rule.ResponseBody == "" {
+		return marshaler.Marshal(resp)
+	}
+
+	m := resp.ProtoReflect()
+	fd := m.Descriptor().Fields().ByName(protoreflect.Name(rule.ResponseBody))
+	if fd == nil {
+		return nil, fmt.Errorf("unknown response_body field %s", rule.ResponseBody)
+	}
+	if fd.Kind() != protoreflect.MessageKind || fd.IsList() || fd.IsMap() {
+		return nil, fmt.Errorf("response_body field %s must be a singular message field", rule.ResponseBody)
+	}
+	return marshaler.Marshal(m.Get(fd).Message().Interface())
+}
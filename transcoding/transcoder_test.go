@@ -0,0 +1,31 @@
+package transcoding
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	_ "github.com/nikita-itmo-gh-acc/car_estimator_api_contracts/gen/feed_v1"
+)
+
+func TestBuildRequestBodyLimit(t *testing.T) {
+	rules, err := ParseRules("POST /listings feed.FeedService/CreateListing body=listing")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := `{"description":"` + strings.Repeat("a", 100) + `"}`
+	r := httptest.NewRequest(http.MethodPost, "/listings", strings.NewReader(body))
+	if _, err := BuildRequest(rules[0], r, nil); err != nil {
+		t.Fatalf("small body: %v", err)
+	}
+
+	body = `{"description":"` + strings.Repeat("a", MaxBodyBytes) + `"}`
+	r = httptest.NewRequest(http.MethodPost, "/listings", strings.NewReader(body))
+	var maxBytes *http.MaxBytesError
+	if _, err := BuildRequest(rules[0], r, nil); !errors.As(err, &maxBytes) {
+		t.Errorf("body over MaxBodyBytes: err = %v, want *http.MaxBytesError", err)
+	}
+}
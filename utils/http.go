@@ -17,7 +17,12 @@ var (
 		codes.PermissionDenied: 403,
 		codes.NotFound: 404,
 		codes.AlreadyExists: 409,
+		codes.FailedPrecondition: 412,
+		codes.ResourceExhausted: 429,
 		codes.Internal: 500,
+		codes.Unimplemented: 501,
+		codes.Unavailable: 503,
+		codes.DeadlineExceeded: 504,
 	}
 )
 
@@ -30,7 +35,9 @@ func HandleResponseErr(w http.ResponseWriter, logger *slog.Logger, msg string, e
 		return 
 	}
 	logger.Error(msg + st.Message())
-	code = CodeMapper[st.Code()]
+	if mapped, ok := CodeMapper[st.Code()]; ok {
+		code = mapped
+	}
 	http.Error(w, msg + st.Message(), code)
 }
 